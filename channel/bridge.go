@@ -0,0 +1,137 @@
+package channel
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type WithBridge[T any] func(*Bridge[T])
+
+// WithCodec sets how items are encoded on the wire, JSONCodec by default
+func WithCodec[T any](codec Codec[T]) WithBridge[T] {
+	return func(b *Bridge[T]) {
+		b.codec = codec
+	}
+}
+
+// WithBridgeErrorHandler receives messages that could not be decoded or delivered locally
+func WithBridgeErrorHandler[T any](fn func(err error)) WithBridge[T] {
+	return func(b *Bridge[T]) {
+		b.onError = fn
+	}
+}
+
+// Bridge connects a local Fanout to the Fanouts of other nodes through a Transport.
+// Send broadcasts locally and publishes to the other nodes, Run delivers what
+// the other nodes publish to the local subscribers.
+type Bridge[T any] struct {
+	node      string
+	fanout    *Fanout[T]
+	transport Transport
+	codec     Codec[T]
+	onError   func(err error)
+}
+
+func NewBridge[T any](fanout *Fanout[T], transport Transport, opts ...WithBridge[T]) *Bridge[T] {
+	bridge := &Bridge[T]{
+		node:      uuid.New().String(),
+		fanout:    fanout,
+		transport: transport,
+		codec:     JSONCodec[T](),
+	}
+
+	for _, opt := range opts {
+		opt(bridge)
+	}
+
+	return bridge
+}
+
+// Send delivers data to the local subscribers then to the other nodes
+func (b *Bridge[T]) Send(ctx context.Context, data T) error {
+	if err := b.fanout.Send(data); err != nil {
+		return err
+	}
+
+	payload, err := b.codec.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode bridge message: %w", err)
+	}
+
+	return b.transport.Publish(ctx, b.encode(payload))
+}
+
+// Run receives messages from the other nodes until ctx is done, the transport fails or the Fanout is closed
+func (b *Bridge[T]) Run(ctx context.Context) error {
+	for {
+		message, err := b.transport.Receive(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return nil
+			}
+			return err
+		}
+
+		node, payload, err := b.decode(message)
+		if err != nil {
+			b.report(err)
+			continue
+		}
+
+		if node == b.node {
+			// our own message echoed by the transport
+			continue
+		}
+
+		data, err := b.codec.Unmarshal(payload)
+		if err != nil {
+			b.report(fmt.Errorf("decode bridge message: %w", err))
+			continue
+		}
+
+		if err := b.fanout.Send(data); err != nil {
+			if errors.Is(err, ErrClosed) {
+				return err
+			}
+			b.report(fmt.Errorf("deliver bridge message: %w", err))
+		}
+	}
+}
+
+// Close closes the transport, the Fanout stays open
+func (b *Bridge[T]) Close() error {
+	return b.transport.Close()
+}
+
+func (b *Bridge[T]) report(err error) {
+	if b.onError != nil {
+		b.onError(err)
+	}
+}
+
+// messages are the node id length (2 bytes), the node id then the codec payload
+func (b *Bridge[T]) encode(payload []byte) []byte {
+	message := make([]byte, 2+len(b.node)+len(payload))
+	binary.BigEndian.PutUint16(message, uint16(len(b.node)))
+	copy(message[2:], b.node)
+	copy(message[2+len(b.node):], payload)
+
+	return message
+}
+
+func (b *Bridge[T]) decode(message []byte) (string, []byte, error) {
+	if len(message) < 2 {
+		return "", nil, errors.New("bridge message too short")
+	}
+
+	size := int(binary.BigEndian.Uint16(message))
+	if len(message) < 2+size {
+		return "", nil, errors.New("bridge message too short")
+	}
+
+	return string(message[2 : 2+size]), message[2+size:], nil
+}
@@ -0,0 +1,383 @@
+package channel
+
+import (
+	"cmp"
+	"context"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// WithRelayTopics caps how many topics a Broker relays, the topic sent to least
+// recently loses its history first. Without it only topics whose items all expired
+// (WithRelayRetention) are forgotten, so bound it when topics are unbounded (e.g. ids).
+func WithRelayTopics[T any](limit int) WithOptions[T] {
+	return func(o *options[T]) {
+		o.relayTopics = limit
+	}
+}
+
+// NewBroker creates a topic based Fanout, WithRelay keeps the last items of every topic
+func NewBroker[K comparable, T any](params ...WithOptions[T]) Broker[K, T] {
+	return Broker[K, T]{
+		core:     newCore(params),
+		rw:       &sync.RWMutex{},
+		subs:     map[string]*topicSubscriber[K, T]{},
+		topics:   map[K]map[string]sink[T]{},
+		patterns: map[string]map[string]sink[T]{},
+		relays:   map[K]*topicRelay[T]{},
+		sweepAt:  &atomic.Int64{},
+	}
+}
+
+// Broker routes every item to the subscribers of its topic only.
+// When K is a string kind, a subscription key containing *, ? or [ is a
+// path.Match pattern (e.g. "price.*") matched against the topic on every Send,
+// exact keys are a map lookup so Send costs O(subscribers of the topic + patterns).
+type Broker[K comparable, T any] struct {
+	core[T]
+	rw       *sync.RWMutex // guards subs, topics, patterns and relays
+	subs     map[string]*topicSubscriber[K, T]
+	topics   map[K]map[string]sink[T]
+	patterns map[string]map[string]sink[T]
+	relays   map[K]*topicRelay[T]
+	sweepAt  *atomic.Int64 // relay count triggering the next eviction of expired topics
+}
+
+type topicRelay[T any] struct {
+	relay *Relay[Item[T]]
+	last  uint64 // seq of the last item, written under mu
+}
+
+type topicSubscriber[K comparable, T any] struct {
+	sink     sink[T]
+	topics   []K
+	patterns []string
+}
+
+// Stats returns a snapshot of the subscribers and counters, Relay sums every topic
+func (b *Broker[K, T]) Stats() Stats {
+	stats := b.counters()
+
+	b.rw.RLock()
+	defer b.rw.RUnlock()
+
+	for id, entry := range b.subs {
+		length, capacity := entry.sink.depth()
+		stats.Queues = append(stats.Queues, QueueStats{
+			ID:     id,
+			Len:    length,
+			Cap:    capacity,
+			Policy: entry.sink.policy(),
+		})
+	}
+	stats.Subscribers = len(stats.Queues)
+
+	for _, relay := range b.relays {
+		stats.Relay += relay.relay.Len()
+	}
+
+	return stats
+}
+
+func (b *Broker[K, T]) Send(topic K, sendingData T) error {
+	sendingData, ok, err := b.admit(sendingData)
+	if !ok {
+		return err
+	}
+
+	b.delivery.Lock()
+	defer b.delivery.Unlock()
+
+	b.mu.Lock()
+	if b.closed.Load() {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+
+	item := Item[T]{Seq: b.seq.Add(1), Data: sendingData}
+	if b.options.relay > 0 {
+		relay := b.relay(topic)
+		relay.relay.Add(item)
+		relay.last = item.Seq
+	}
+	b.mu.Unlock()
+
+	b.sent.Add(1)
+	if b.options.metrics != nil {
+		b.options.metrics.OnSent(item.Seq)
+	}
+
+	for id, sub := range b.targets(topic) {
+		b.handle(id, sub, item, sub.push(item, b.dropOldest), b.unsubscribe)
+	}
+
+	return nil
+}
+
+// targets collects the subscribers of topic, a subscriber matching through
+// several keys or patterns is returned once
+func (b *Broker[K, T]) targets(topic K) map[string]sink[T] {
+	b.rw.RLock()
+	defer b.rw.RUnlock()
+
+	result := make(map[string]sink[T], len(b.topics[topic]))
+	for id, sub := range b.topics[topic] {
+		result[id] = sub
+	}
+
+	if len(b.patterns) == 0 {
+		return result
+	}
+
+	name, ok := topicName(topic)
+	if !ok {
+		return result
+	}
+
+	for pattern, subs := range b.patterns {
+		if matched, _ := path.Match(pattern, name); !matched {
+			continue
+		}
+
+		for id, sub := range subs {
+			result[id] = sub
+		}
+	}
+
+	return result
+}
+
+// relay returns the relay of topic, creating it when missing, must be called with mu held
+func (b *Broker[K, T]) relay(topic K) *topicRelay[T] {
+	b.rw.RLock()
+	relay, ok := b.relays[topic]
+	b.rw.RUnlock()
+
+	if ok {
+		return relay
+	}
+
+	b.rw.Lock()
+	defer b.rw.Unlock()
+
+	b.evictRelays()
+
+	relay = &topicRelay[T]{relay: NewRelay[Item[T]](b.options.relay, b.options.relayRetention)}
+	b.relays[topic] = relay
+
+	return relay
+}
+
+// evictRelays makes room for a new topic, must be called with rw held.
+// Expired topics are swept each time the count doubles so the cost stays amortized.
+func (b *Broker[K, T]) evictRelays() {
+	if b.options.relayRetention > 0 && int64(len(b.relays)) >= b.sweepAt.Load() {
+		for topic, relay := range b.relays {
+			if relay.relay.Len() == 0 {
+				delete(b.relays, topic)
+			}
+		}
+		b.sweepAt.Store(max(int64(len(b.relays))*2, 64))
+	}
+
+	if b.options.relayTopics <= 0 || len(b.relays) < b.options.relayTopics {
+		return
+	}
+
+	var oldest K
+	var oldestSeq uint64
+	found := false
+	for topic, relay := range b.relays {
+		if !found || relay.last < oldestSeq {
+			oldest, oldestSeq, found = topic, relay.last, true
+		}
+	}
+
+	delete(b.relays, oldest)
+}
+
+// Close unsubscribes every subscriber and makes further Send calls fail with ErrClosed
+func (b *Broker[K, T]) Close() {
+	b.close(b.unsubscribeAll)
+}
+
+// Wait subscribes to the given topics, see Fanout.Wait
+func (b *Broker[K, T]) Wait(buffer int, topics []K, opts ...WithSubscriber) (chan T, func()) {
+	sub := newSubscriber(buffer, itemData[T], identity[T], nil, newSubscriberOptions(opts))
+	id, _ := b.subscribe(sub, topics)
+
+	return sub.ch, func() {
+		b.unsubscribe(id)
+	}
+}
+
+// Subscribe subscribes to the given topics until ctx is done, see Fanout.Subscribe
+func (b *Broker[K, T]) Subscribe(ctx context.Context, topics []K, opts ...WithSubscriber) (<-chan T, error) {
+	subOpts := newSubscriberOptions(opts)
+	sub := newSubscriber(subOpts.buffer, itemData[T], identity[T], nil, subOpts)
+
+	id, err := b.subscribe(sub, topics)
+	if err != nil {
+		return nil, err
+	}
+
+	unsubscribeOnDone(ctx, sub.done, func() {
+		b.unsubscribe(id)
+	})
+
+	return sub.ch, nil
+}
+
+func (b *Broker[K, T]) subscribe(sub sink[T], topics []K) (string, error) {
+	id := uuid.New().String()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed.Load() {
+		sub.close()
+		return id, ErrClosed
+	}
+
+	entry := &topicSubscriber[K, T]{sink: sub}
+	for _, topic := range topics {
+		if name, ok := topicName(topic); ok && isPattern(name) {
+			entry.patterns = append(entry.patterns, name)
+			continue
+		}
+
+		entry.topics = append(entry.topics, topic)
+	}
+
+	b.rw.Lock()
+	defer b.rw.Unlock()
+
+	replaying := sub.startReplay(b.history(entry), b.seq.Load())
+
+	b.subs[id] = entry
+	for _, topic := range entry.topics {
+		if b.topics[topic] == nil {
+			b.topics[topic] = map[string]sink[T]{}
+		}
+		b.topics[topic][id] = sub
+	}
+	for _, pattern := range entry.patterns {
+		if b.patterns[pattern] == nil {
+			b.patterns[pattern] = map[string]sink[T]{}
+		}
+		b.patterns[pattern][id] = sub
+	}
+
+	b.observeSubscribers(len(b.subs))
+
+	if replaying {
+		go replay(sub, b.dropOldest, func(item Item[T], result deliveryResult) {
+			b.handle(id, sub, item, result, b.unsubscribe)
+		})
+	}
+
+	return id, nil
+}
+
+// history merges the relays of every topic the subscriber matches, in sequence order
+func (b *Broker[K, T]) history(entry *topicSubscriber[K, T]) []Item[T] {
+	if len(b.relays) == 0 {
+		return nil
+	}
+
+	var result []Item[T]
+	for topic, relay := range b.relays {
+		if entry.matches(topic) {
+			result = append(result, relay.relay.Get()...)
+		}
+	}
+
+	slices.SortFunc(result, func(a, b Item[T]) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+
+	return result
+}
+
+func (b *Broker[K, T]) unsubscribe(id string) {
+	b.rw.Lock()
+	entry, ok := b.subs[id]
+	if ok {
+		delete(b.subs, id)
+		for _, topic := range entry.topics {
+			delete(b.topics[topic], id)
+			if len(b.topics[topic]) == 0 {
+				delete(b.topics, topic)
+			}
+		}
+		for _, pattern := range entry.patterns {
+			delete(b.patterns[pattern], id)
+			if len(b.patterns[pattern]) == 0 {
+				delete(b.patterns, pattern)
+			}
+		}
+	}
+	count := len(b.subs)
+	b.rw.Unlock()
+
+	if ok {
+		entry.sink.close()
+		b.observeSubscribers(count)
+	}
+}
+
+func (b *Broker[K, T]) unsubscribeAll() {
+	b.rw.RLock()
+	ids := make([]string, 0, len(b.subs))
+	for id := range b.subs {
+		ids = append(ids, id)
+	}
+	b.rw.RUnlock()
+
+	for _, id := range ids {
+		b.unsubscribe(id)
+	}
+}
+
+func (s *topicSubscriber[K, T]) matches(topic K) bool {
+	if slices.Contains(s.topics, topic) {
+		return true
+	}
+
+	if len(s.patterns) == 0 {
+		return false
+	}
+
+	name, ok := topicName(topic)
+	if !ok {
+		return false
+	}
+
+	for _, pattern := range s.patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// topicName returns the key as a string when K is a string kind, wildcards only apply to those
+func topicName[K comparable](topic K) (string, bool) {
+	value := reflect.ValueOf(topic)
+	if value.Kind() != reflect.String {
+		return "", false
+	}
+
+	return value.String(), true
+}
+
+func isPattern(key string) bool {
+	return strings.ContainsAny(key, "*?[")
+}
@@ -0,0 +1,53 @@
+package channel
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/goccy/go-json"
+)
+
+// Codec encodes items sent through a Transport
+type Codec[T any] interface {
+	Marshal(data T) ([]byte, error)
+	Unmarshal(payload []byte) (T, error)
+}
+
+type jsonCodec[T any] struct{}
+
+// JSONCodec encodes items with goccy/go-json
+func JSONCodec[T any]() Codec[T] {
+	return jsonCodec[T]{}
+}
+
+func (jsonCodec[T]) Marshal(data T) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+func (jsonCodec[T]) Unmarshal(payload []byte) (T, error) {
+	var data T
+	err := json.Unmarshal(payload, &data)
+	return data, err
+}
+
+type gobCodec[T any] struct{}
+
+// GobCodec encodes items with encoding/gob, interface values must be registered with gob.Register
+func GobCodec[T any]() Codec[T] {
+	return gobCodec[T]{}
+}
+
+func (gobCodec[T]) Marshal(data T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gobCodec[T]) Unmarshal(payload []byte) (T, error) {
+	var data T
+	err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&data)
+	return data, err
+}
@@ -0,0 +1,142 @@
+package channel
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// core is what Fanout and Broker share: the send locks, the counters, side effects,
+// middlewares and overflow accounting. The owner keeps the subscribers and tells core
+// how to remove them.
+type core[T any] struct {
+	mu       *sync.Mutex // numbers and relays items, subscribe snapshots under it
+	delivery *sync.Mutex // one item delivered at a time, outside of mu
+	closed   *atomic.Bool
+	seq      *atomic.Uint64
+	sent     *atomic.Uint64 // by this process, seq resumes after a persisted relay
+	filtered *atomic.Uint64
+	dropped  *atomic.Uint64
+	effects  *sideEffects[T]
+	options  options[T]
+}
+
+func newCore[T any](params []WithOptions[T]) core[T] {
+	opts := options[T]{}
+	for idx := range params {
+		params[idx](&opts)
+	}
+
+	return core[T]{
+		mu:       &sync.Mutex{},
+		delivery: &sync.Mutex{},
+		closed:   &atomic.Bool{},
+		seq:      &atomic.Uint64{},
+		sent:     &atomic.Uint64{},
+		filtered: &atomic.Uint64{},
+		dropped:  &atomic.Uint64{},
+		effects:  newSideEffects(opts),
+		options:  opts,
+	}
+}
+
+// Seq returns the sequence number of the last broadcast item, 0 if nothing has been sent yet
+func (c *core[T]) Seq() uint64 {
+	return c.seq.Load()
+}
+
+// Dropped returns how many items were not delivered because of overflow policies
+func (c *core[T]) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// admit runs the side effects then the middlewares, ok is false when the item
+// must not be sent, err is ErrClosed when that is because of Close
+func (c *core[T]) admit(data T) (T, bool, error) {
+	if c.closed.Load() {
+		return data, false, ErrClosed
+	}
+
+	c.effects.run(data)
+
+	for idx := range c.options.middlewares {
+		var ok bool
+		if data, ok = c.options.middlewares[idx](data); !ok {
+			// filter data
+			c.filtered.Add(1)
+			if c.options.metrics != nil {
+				c.options.metrics.OnFiltered()
+			}
+			return data, false, nil
+		}
+	}
+
+	return data, true, nil
+}
+
+// counters returns a Stats holding only the counters, the owner adds queues and relay
+func (c *core[T]) counters() Stats {
+	return Stats{
+		Sent:     c.sent.Load(),
+		Filtered: c.filtered.Load(),
+		Dropped:  c.dropped.Load(),
+	}
+}
+
+// close marks the owner closed and sweeps its subscribers, false when it already was.
+// Sweeping first wakes up a Send blocked on a slow subscriber, sweeping again once no
+// subscribe is in flight (mu) catches a concurrent one.
+func (c *core[T]) close(unsubscribeAll func()) bool {
+	if c.closed.Swap(true) {
+		return false
+	}
+
+	unsubscribeAll()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	unsubscribeAll()
+	return true
+}
+
+func (c *core[T]) handle(id string, sub sink[T], item Item[T], result deliveryResult, unsubscribe func(id string)) {
+	switch result {
+	case dropped:
+		c.drop(item.Data, sub.policy())
+	case disconnected:
+		c.drop(item.Data, sub.policy())
+		unsubscribe(id)
+	}
+}
+
+func (c *core[T]) dropOldest(data T) {
+	c.drop(data, OverflowDropOldest)
+}
+
+func (c *core[T]) drop(data T, policy OverflowPolicy) {
+	c.dropped.Add(1)
+	if c.options.metrics != nil {
+		c.options.metrics.OnDropped(policy)
+	}
+	if c.options.onDrop != nil {
+		c.options.onDrop(data, policy)
+	}
+}
+
+func (c *core[T]) observeSubscribers(count int) {
+	if c.options.metrics != nil {
+		c.options.metrics.OnSubscribers(count)
+	}
+}
+
+// unsubscribeOnDone ends a Subscribe subscription with ctx, done is closed by unsubscribe
+func unsubscribeOnDone(ctx context.Context, done <-chan struct{}, unsubscribe func()) {
+	go func() {
+		select {
+		case <-ctx.Done():
+			unsubscribe()
+		case <-done:
+		}
+	}()
+}
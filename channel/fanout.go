@@ -1,127 +1,278 @@
 package channel
 
 import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+
 	"github.com/google/uuid"
 	cmap "github.com/orcaman/concurrent-map/v2"
 	"github.com/rs/zerolog/log"
-	"sync"
 )
 
-func NewFanout[T any](params ...WithOptions[T]) Fanout[T] {
-	opts := options[T]{}
-	for idx := range params {
-		params[idx](&opts)
-	}
+// ErrClosed is returned when sending to or subscribing on a closed Fanout
+var ErrClosed = errors.New("fanout closed")
 
+var (
+	// ErrOffsetEvicted is returned by WaitFrom when items after the offset are no longer relayed
+	ErrOffsetEvicted = errors.New("offset evicted from relay")
+	// ErrOffsetAhead is returned by WaitFrom when the offset has not been reached yet
+	ErrOffsetAhead = errors.New("offset ahead of fanout")
+)
+
+func NewFanout[T any](params ...WithOptions[T]) Fanout[T] {
 	ins := Fanout[T]{
-		data:    cmap.New[*SingleData[T]](),
-		options: opts,
+		core: newCore(params),
+		data: cmap.New[sink[T]](),
 	}
+	opts := ins.options
 
 	if opts.relay > 0 {
-		ins.relay = &RelaySlice{
-			mu:   &sync.RWMutex{},
-			data: nil,
-			size: opts.relay,
+		ins.relay = memoryRelayStore[T]{relay: NewRelay[Item[T]](opts.relay, opts.relayRetention)}
+	}
+
+	if opts.relayStore != nil {
+		ins.relay = opts.relayStore
+
+		// continue the sequence of a persisted relay
+		history, err := ins.relay.Get()
+		if err != nil {
+			log.Error().Err(err).Msg("Fail to load relay store")
+		}
+		if len(history) > 0 {
+			ins.seq.Store(history[len(history)-1].Seq)
 		}
 	}
 
 	return ins
 }
 
+// Fanout broadcasts every item to all subscribers.
+// Items are numbered and relayed under mu, which is what lets Wait register a subscriber
+// and snapshot the relay without missing or repeating an item. Delivery happens one item
+// at a time (delivery) outside of mu, so a blocked subscriber does not stall new ones.
 type Fanout[T any] struct {
-	data    cmap.ConcurrentMap[string, *SingleData[T]]
-	relay   *RelaySlice
-	options options[T]
+	core[T]
+	data  cmap.ConcurrentMap[string, sink[T]]
+	relay RelayStore[T]
 }
 
-type SingleData[T any] struct {
-	ch     chan T
-	closed bool
-	ignore func(T) bool
+// Stats returns a snapshot of the subscribers and counters
+func (f *Fanout[T]) Stats() Stats {
+	stats := f.counters()
+
+	for m := range f.data.IterBuffered() {
+		length, capacity := m.Val.depth()
+		stats.Queues = append(stats.Queues, QueueStats{
+			ID:     m.Key,
+			Len:    length,
+			Cap:    capacity,
+			Policy: m.Val.policy(),
+		})
+	}
+	stats.Subscribers = len(stats.Queues)
+
+	if f.relay != nil {
+		stats.Relay = f.relay.Len()
+	}
+
+	return stats
 }
 
-func (f *Fanout[T]) Send(sendingData T) {
-	defer func() {
-		// Quick hack
-		// Do ko đc phép close khi có nhiều subscriber nhưng mà đang quick hack nên tạm thời để như này đã
-		if r := recover(); r != nil {
-			log.Error().Any("err", r).Msg("Fail to handle channel")
-		}
-	}()
+func (f *Fanout[T]) Send(sendingData T) error {
+	sendingData, ok, err := f.admit(sendingData)
+	if !ok {
+		return err
+	}
+
+	f.delivery.Lock()
+	defer f.delivery.Unlock()
 
-	for idx := range f.options.sideEffect {
-		// show something like log here
-		go f.options.sideEffect[idx](sendingData)
+	f.mu.Lock()
+	if f.closed.Load() {
+		f.mu.Unlock()
+		return ErrClosed
 	}
 
-	for idx := range f.options.middlewares {
-		if !f.options.middlewares[idx](sendingData) {
-			// filter data
-			return
+	item := Item[T]{Seq: f.seq.Add(1), Data: sendingData}
+
+	// live subscribers still get the item, WaitFrom notices the hole in the relay
+	if f.relay != nil {
+		if err := f.relay.Add(item); err != nil {
+			log.Error().Err(err).Uint64("seq", item.Seq).Msg("Fail to add item to relay store")
 		}
 	}
+	f.mu.Unlock()
 
-	if f.relay != nil {
-		go f.relay.Add(sendingData)
+	f.sent.Add(1)
+	if f.options.metrics != nil {
+		f.options.metrics.OnSent(item.Seq)
 	}
 
 	for m := range f.data.IterBuffered() {
-		if m.Val.closed {
-			continue
-		}
-
-		if m.Val.ignore != nil && m.Val.ignore(sendingData) {
+		if !m.Val.accepts(sendingData) {
 			continue
 		}
 
-		m.Val.ch <- sendingData
+		f.handle(m.Key, m.Val, item, m.Val.push(item, f.dropOldest), f.unsubscribe)
 	}
+
+	return nil
+}
+
+// Close unsubscribes every subscriber and makes further Send calls fail with ErrClosed
+func (f *Fanout[T]) Close() {
+	f.close(f.unsubscribeAll)
 }
 
 // Wait
 // buffer -> channel size
 // ignore -> tương tự filter bên js
-func (f *Fanout[T]) Wait(buffer int, ignore func(T) bool) (chan T, func()) {
-	ch := make(chan T, buffer)
+// opts -> overflow policy when the buffer is full, block by default
+//
+// The relay history is delivered first, then live items, in sequence order.
+// The returned channel is already closed when the Fanout is closed.
+func (f *Fanout[T]) Wait(buffer int, ignore func(T) bool, opts ...WithSubscriber) (chan T, func()) {
+	sub := newSubscriber(buffer, itemData[T], identity[T], ignore, newSubscriberOptions(opts))
+	id, _ := f.subscribe(sub)
 
-	id := uuid.New().String()
-	f.data.Set(id, &SingleData[T]{
-		ch:     ch,
-		closed: false,
-		ignore: ignore,
+	return sub.ch, func() {
+		f.unsubscribe(id)
+	}
+}
+
+// WaitItems works like Wait but exposes the sequence number of every item
+func (f *Fanout[T]) WaitItems(buffer int, ignore func(T) bool, opts ...WithSubscriber) (chan Item[T], func()) {
+	sub := newSubscriber(buffer, identity[Item[T]], itemData[T], ignore, newSubscriberOptions(opts))
+	id, _ := f.subscribe(sub)
+
+	return sub.ch, func() {
+		f.unsubscribe(id)
+	}
+}
+
+// Subscribe works like Wait (use WithBuffer for the channel size) but the subscription
+// ends and the channel is closed as soon as ctx is done, so there is nothing to release
+func (f *Fanout[T]) Subscribe(ctx context.Context, opts ...WithSubscriber) (<-chan T, error) {
+	subOpts := newSubscriberOptions(opts)
+	sub := newSubscriber(subOpts.buffer, itemData[T], identity[T], nil, subOpts)
+
+	id, err := f.subscribe(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	unsubscribeOnDone(ctx, sub.done, func() {
+		f.unsubscribe(id)
 	})
 
-	go func() {
-		// sending relay data
-		if f.relay == nil {
-			return
-		}
+	return sub.ch, nil
+}
+
+// WaitFrom works like WaitItems but only replays the items after offset, the Seq of the
+// last item the consumer has seen (e.g. an SSE Last-Event-ID). When some of those items
+// are not relayed anymore it returns ErrOffsetEvicted and the caller should resync.
+func (f *Fanout[T]) WaitFrom(offset uint64, buffer int, ignore func(T) bool, opts ...WithSubscriber) (chan Item[T], func(), error) {
+	sub := newSubscriber(buffer, identity[Item[T]], itemData[T], ignore, newSubscriberOptions(opts))
+	id, err := f.subscribeFrom(sub, func(history []Item[T]) ([]Item[T], error) {
+		return f.after(offset, history)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sub.ch, func() {
+		f.unsubscribe(id)
+	}, nil
+}
+
+// subscribe registers sub and replays the whole relay
+func (f *Fanout[T]) subscribe(sub sink[T]) (string, error) {
+	return f.subscribeFrom(sub, func(history []Item[T]) ([]Item[T], error) {
+		return history, nil
+	})
+}
+
+// after returns the relayed items following offset, must be called with mu held
+func (f *Fanout[T]) after(offset uint64, history []Item[T]) ([]Item[T], error) {
+	last := f.seq.Load()
+	if offset > last {
+		return nil, ErrOffsetAhead
+	}
+
+	if offset == last {
+		return nil, nil
+	}
+
+	if len(history) == 0 || history[0].Seq > offset+1 {
+		return nil, ErrOffsetEvicted
+	}
+
+	idx, _ := slices.BinarySearchFunc(history, offset+1, func(item Item[T], seq uint64) int {
+		return cmp.Compare(item.Seq, seq)
+	})
+
+	// an item the relay store failed to keep is missing from the middle
+	if uint64(len(history)-idx) != last-offset {
+		return nil, ErrOffsetEvicted
+	}
 
-		relayedData := f.relay.Get()
-		for idx := range relayedData {
-			if ignore != nil && ignore(relayedData[idx].(T)) {
-				continue
-			}
+	return history[idx:], nil
+}
+
+// subscribeFrom registers sub while no item is in flight, so the relay snapshot
+// ends exactly where its live items begin, selectHistory picks the part to replay
+func (f *Fanout[T]) subscribeFrom(sub sink[T], selectHistory func([]Item[T]) ([]Item[T], error)) (string, error) {
+	id := uuid.New().String()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed.Load() {
+		sub.close()
+		return id, ErrClosed
+	}
 
-			ch <- relayedData[idx].(T)
+	var history []Item[T]
+	if f.relay != nil {
+		var err error
+		if history, err = f.relay.Get(); err != nil {
+			// still subscribe to live items, WaitFrom reports the missing history as evicted
+			log.Error().Err(err).Msg("Fail to read relay store")
 		}
-	}()
-
-	return ch, func() {
-		f.data.RemoveCb(id, func(key string, v *SingleData[T], exists bool) bool {
-			defer func() {
-				if r := recover(); r != nil {
-					log.Error().Any("request", r).Msg("[CRITICAL] Fail to handling close fanout")
-				}
-			}()
-
-			if exists && v != nil && !v.closed {
-				v.closed = true
-				close(v.ch)
-			}
-
-			return true
+	}
+
+	history, err := selectHistory(history)
+	if err != nil {
+		sub.close()
+		return id, err
+	}
+
+	replaying := sub.startReplay(history, f.seq.Load())
+
+	f.data.Set(id, sub)
+	f.observeSubscribers(f.data.Count())
+
+	if replaying {
+		go replay(sub, f.dropOldest, func(item Item[T], result deliveryResult) {
+			f.handle(id, sub, item, result, f.unsubscribe)
 		})
 	}
+
+	return id, nil
+}
+
+// unsubscribe removes the subscriber and closes its channel, calling it more than once is a no-op
+func (f *Fanout[T]) unsubscribe(id string) {
+	if sub, ok := f.data.Pop(id); ok {
+		sub.close()
+		f.observeSubscribers(f.data.Count())
+	}
+}
+
+func (f *Fanout[T]) unsubscribeAll() {
+	for _, id := range f.data.Keys() {
+		f.unsubscribe(id)
+	}
 }
@@ -0,0 +1,204 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestFanoutChurn sends from several goroutines while subscribers come and go
+// and the Fanout gets closed, run it with -race
+func TestFanoutChurn(t *testing.T) {
+	const (
+		senders     = 8
+		subscribers = 16
+		rounds      = 50
+	)
+
+	fanout := NewFanout[int](WithRelay[int](32))
+
+	stop := make(chan struct{})
+	var sending sync.WaitGroup
+	for sender := range senders {
+		sending.Add(1)
+		go func() {
+			defer sending.Done()
+
+			for value := sender; ; value += senders {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				if err := fanout.Send(value); err != nil {
+					if !errors.Is(err, ErrClosed) {
+						t.Errorf("send: %v", err)
+					}
+					return
+				}
+			}
+		}()
+	}
+
+	var receiving sync.WaitGroup
+	for sub := range subscribers {
+		receiving.Add(1)
+		go func() {
+			defer receiving.Done()
+
+			for round := range rounds {
+				var ch chan Item[int]
+				var release func()
+
+				switch sub % 3 {
+				case 0:
+					ch, release = fanout.WaitItems(4, nil)
+				case 1:
+					ch, release = fanout.WaitItems(4, nil, WithOverflow(OverflowDropOldest))
+				default:
+					ch, release = fanout.WaitItems(1, nil, WithDisconnectAfter(time.Millisecond))
+				}
+
+				var last uint64
+				for count := 0; count < round%7+1; count++ {
+					item, ok := <-ch
+					if !ok {
+						break
+					}
+
+					if item.Seq <= last {
+						t.Errorf("subscriber %d got seq %d after %d", sub, item.Seq, last)
+					}
+					last = item.Seq
+				}
+
+				release()
+				release()
+
+				// release closes the channel, whatever is left must stay in order
+				for item := range ch {
+					if item.Seq <= last {
+						t.Errorf("subscriber %d got seq %d after %d", sub, item.Seq, last)
+					}
+					last = item.Seq
+				}
+			}
+		}()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := fanout.Subscribe(ctx, WithBuffer(8), WithOverflow(OverflowDropNewest))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	for range ch {
+	}
+
+	receiving.Wait()
+
+	fanout.Close()
+	close(stop)
+	sending.Wait()
+
+	if err := fanout.Send(0); !errors.Is(err, ErrClosed) {
+		t.Fatalf("send after close: %v", err)
+	}
+
+	late, _ := fanout.Wait(1, nil)
+	if _, ok := <-late; ok {
+		t.Fatal("wait after close should return a closed channel")
+	}
+
+	if stats := fanout.Stats(); stats.Subscribers != 0 {
+		t.Fatalf("%d subscribers left after close", stats.Subscribers)
+	}
+}
+
+// TestFanoutCloseWhileBlocked closes the Fanout while Send waits on a full subscriber
+func TestFanoutCloseWhileBlocked(t *testing.T) {
+	fanout := NewFanout[int]()
+	ch, _ := fanout.Wait(0, nil)
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- fanout.Send(1)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	fanout.Close()
+
+	select {
+	case err := <-sent:
+		if err != nil && !errors.Is(err, ErrClosed) {
+			t.Fatalf("send: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Send still blocked after Close")
+	}
+
+	for range ch {
+	}
+}
+
+// TestFanoutWaitWhileSendBlocked subscribes while Send waits on a full subscriber,
+// the new subscriber gets the relay then only the items sent after it
+func TestFanoutWaitWhileSendBlocked(t *testing.T) {
+	fanout := NewFanout[int](WithRelay[int](8))
+	slow, releaseSlow := fanout.Wait(0, nil)
+	defer releaseSlow()
+
+	sent := make(chan error, 1)
+	go func() {
+		sent <- fanout.Send(1)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	subscribed := make(chan chan Item[int], 1)
+	go func() {
+		ch, _ := fanout.WaitItems(8, nil)
+		subscribed <- ch
+	}()
+
+	var ch chan Item[int]
+	select {
+	case ch = <-subscribed:
+	case <-time.After(time.Second):
+		t.Fatal("Wait blocked behind Send")
+	}
+
+	<-slow
+	if err := <-sent; err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-slow
+	}()
+	if err := fanout.Send(2); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []uint64{1, 2} {
+		if item := <-ch; item.Seq != want {
+			t.Fatalf("got seq %d, want %d", item.Seq, want)
+		}
+	}
+
+	select {
+	case item := <-ch:
+		t.Fatalf("unexpected item %d", item.Seq)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
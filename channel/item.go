@@ -0,0 +1,17 @@
+package channel
+
+// Item is a broadcast value together with its sequence number.
+// Sequence numbers start at 1 and increase by one for every item that passes
+// the middlewares, so a consumer can detect gaps or duplicates.
+type Item[T any] struct {
+	Seq  uint64
+	Data T
+}
+
+func itemData[T any](item Item[T]) T {
+	return item.Data
+}
+
+func identity[T any](v T) T {
+	return v
+}
@@ -1,9 +1,114 @@
 package channel
 
+import (
+	"sync"
+	"time"
+)
+
+// Middleware filters items, returning false drops the item
 type Middleware[T any] func(T) bool
 
+// Transform returns the item to broadcast instead of data (enrich, redact...),
+// returning false drops the item
+type Transform[T any] func(data T) (T, bool)
+
 func WithMiddleware[T any](middlewares ...Middleware[T]) func(*options[T]) {
 	return func(o *options[T]) {
-		o.middlewares = append(o.middlewares, middlewares...)
+		for idx := range middlewares {
+			o.middlewares = append(o.middlewares, middlewares[idx].transform)
+		}
+	}
+}
+
+// WithTransform appends transforms to the middleware chain, filters and transforms run in registration order
+func WithTransform[T any](transforms ...Transform[T]) WithOptions[T] {
+	return func(o *options[T]) {
+		o.middlewares = append(o.middlewares, transforms...)
+	}
+}
+
+func (m Middleware[T]) transform(data T) (T, bool) {
+	return data, m(data)
+}
+
+// Dedupe drops an item when another one with the same key passed within window
+func Dedupe[T any, K comparable](key func(T) K, window time.Duration) Transform[T] {
+	var (
+		mu        sync.Mutex
+		seen      = map[K]time.Time{}
+		lastSweep = time.Now()
+	)
+
+	return func(data T) (T, bool) {
+		now := time.Now()
+		k := key(data)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		// forget expired keys once per window so the map does not grow forever
+		if now.Sub(lastSweep) > window {
+			for sk, at := range seen {
+				if now.Sub(at) > window {
+					delete(seen, sk)
+				}
+			}
+			lastSweep = now
+		}
+
+		if at, ok := seen[k]; ok && now.Sub(at) <= window {
+			return data, false
+		}
+
+		seen[k] = now
+		return data, true
+	}
+}
+
+// RateLimit lets through at most limit items per interval (token bucket, bursts up to limit)
+func RateLimit[T any](limit int, interval time.Duration) Transform[T] {
+	var (
+		mu     sync.Mutex
+		tokens = float64(limit)
+		last   = time.Now()
+		rate   = float64(limit) / float64(interval)
+	)
+
+	return func(data T) (T, bool) {
+		now := time.Now()
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		tokens = min(float64(limit), tokens+float64(now.Sub(last))*rate)
+		last = now
+
+		if tokens < 1 {
+			return data, false
+		}
+
+		tokens--
+		return data, true
+	}
+}
+
+// Sample lets through one item out of every n
+func Sample[T any](n int) Transform[T] {
+	var (
+		mu    sync.Mutex
+		count int
+	)
+
+	return func(data T) (T, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		count++
+		if count < n {
+			return data, false
+		}
+
+		count = 0
+		return data, true
 	}
 }
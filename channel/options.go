@@ -1,9 +1,23 @@
 package channel
 
+import (
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+)
+
 type WithOptions[T any] func(*options[T])
 
 type options[T any] struct {
-	middlewares []Middleware[T]
-	sideEffect  []func(T)
-	relay       int
+	middlewares       []Transform[T]
+	sideEffect        []func(T)
+	sideEffectPool    *ants.Pool
+	onSideEffectError func(T, error)
+	sideEffectQueue   int
+	relay             int
+	relayRetention    time.Duration
+	relayStore        RelayStore[T]
+	relayTopics       int
+	onDrop            DropHandler[T]
+	metrics           MetricsHook
 }
@@ -0,0 +1,80 @@
+package channel
+
+import "time"
+
+// OverflowPolicy decides what Send does when a subscriber buffer is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until the subscriber reads, stalling Send (default)
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the item that does not fit
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest buffered item to make room
+	OverflowDropOldest
+	// OverflowDisconnect waits up to the timeout then unsubscribes the subscriber
+	OverflowDisconnect
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop_newest"
+	case OverflowDropOldest:
+		return "drop_oldest"
+	case OverflowDisconnect:
+		return "disconnect"
+	default:
+		return "unknown"
+	}
+}
+
+// DropHandler is called every time an item is not delivered to a subscriber
+type DropHandler[T any] func(data T, policy OverflowPolicy)
+
+func WithDropHandler[T any](fn DropHandler[T]) WithOptions[T] {
+	return func(o *options[T]) {
+		o.onDrop = fn
+	}
+}
+
+type WithSubscriber func(*subscriberOptions)
+
+type subscriberOptions struct {
+	buffer   int
+	overflow OverflowPolicy
+	timeout  time.Duration
+}
+
+// WithBuffer sets the channel size of a Subscribe subscription, Wait takes it as an argument
+func WithBuffer(buffer int) WithSubscriber {
+	return func(o *subscriberOptions) {
+		o.buffer = buffer
+	}
+}
+
+// WithOverflow sets the policy applied when the subscriber buffer is full
+func WithOverflow(policy OverflowPolicy) WithSubscriber {
+	return func(o *subscriberOptions) {
+		o.overflow = policy
+	}
+}
+
+// WithDisconnectAfter unsubscribes a subscriber that stays full for longer than timeout
+func WithDisconnectAfter(timeout time.Duration) WithSubscriber {
+	return func(o *subscriberOptions) {
+		o.overflow = OverflowDisconnect
+		o.timeout = timeout
+	}
+}
+
+func newSubscriberOptions(opts []WithSubscriber) subscriberOptions {
+	subOpts := subscriberOptions{overflow: OverflowBlock}
+	for idx := range opts {
+		opts[idx](&subOpts)
+	}
+
+	return subOpts
+}
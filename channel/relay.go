@@ -1,6 +1,9 @@
 package channel
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
 func WithRelay[T any](relay int) func(*options[T]) {
 	return func(o *options[T]) {
@@ -8,27 +11,99 @@ func WithRelay[T any](relay int) func(*options[T]) {
 	}
 }
 
-type RelaySlice struct {
-	mu   *sync.RWMutex
-	data []any
-	size int
+// WithRelayRetention drops relayed items older than retention, on top of the WithRelay size limit
+func WithRelayRetention[T any](retention time.Duration) WithOptions[T] {
+	return func(o *options[T]) {
+		o.relayRetention = retention
+	}
+}
+
+type relayEntry[T any] struct {
+	at    time.Time
+	value T
+}
+
+// Relay is a fixed-capacity ring buffer keeping the last N items,
+// optionally only those added within the retention window.
+// Add does not allocate, Get returns a copy that is safe to keep.
+type Relay[T any] struct {
+	mu        *sync.RWMutex
+	data      []relayEntry[T]
+	head      int // index of the oldest item
+	size      int
+	retention time.Duration
+}
+
+// NewRelay creates a relay holding up to capacity items, retention <= 0 keeps items until they are overwritten
+func NewRelay[T any](capacity int, retention time.Duration) *Relay[T] {
+	return &Relay[T]{
+		mu:        &sync.RWMutex{},
+		data:      make([]relayEntry[T], max(capacity, 1)),
+		retention: retention,
+	}
 }
 
-func (r *RelaySlice) Add(data any) {
+func (r *Relay[T]) Add(data T) {
+	now := time.Now()
+
 	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	r.data = append(r.data, data)
+	r.expire(now)
 
-	// Remove first item to keep relay size
-	if len(r.data) > r.size {
-		r.data = r.data[len(r.data)-r.size:]
+	if r.size == len(r.data) {
+		// overwrite the oldest item to keep relay size
+		r.data[r.head] = relayEntry[T]{at: now, value: data}
+		r.head = (r.head + 1) % len(r.data)
+		return
 	}
 
-	r.mu.Unlock()
+	r.data[(r.head+r.size)%len(r.data)] = relayEntry[T]{at: now, value: data}
+	r.size++
 }
 
-func (r *RelaySlice) Get() []any {
+// Get returns a snapshot of the relayed items, oldest first
+func (r *Relay[T]) Get() []T {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.data
+
+	skip := r.expired(time.Now())
+	result := make([]T, 0, r.size-skip)
+	for idx := skip; idx < r.size; idx++ {
+		result = append(result, r.data[(r.head+idx)%len(r.data)].value)
+	}
+
+	return result
+}
+
+// Len returns how many items are currently relayed
+func (r *Relay[T]) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.size - r.expired(time.Now())
+}
+
+// expired counts the items at the head of the buffer that are older than retention
+func (r *Relay[T]) expired(now time.Time) int {
+	if r.retention <= 0 {
+		return 0
+	}
+
+	count := 0
+	for count < r.size && now.Sub(r.data[(r.head+count)%len(r.data)].at) > r.retention {
+		count++
+	}
+
+	return count
+}
+
+func (r *Relay[T]) expire(now time.Time) {
+	var zero relayEntry[T]
+	for count := r.expired(now); count > 0; count-- {
+		// release the reference so the value can be collected
+		r.data[r.head] = zero
+		r.head = (r.head + 1) % len(r.data)
+		r.size--
+	}
 }
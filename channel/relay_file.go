@@ -0,0 +1,334 @@
+package channel
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+const segmentExt = ".seg"
+
+type WithFileRelay[T any] func(*FileRelayStore[T])
+
+// WithSegmentSize sets the size in bytes after which a new segment file is started, 4MB by default
+func WithSegmentSize[T any](size int64) WithFileRelay[T] {
+	return func(s *FileRelayStore[T]) {
+		s.segmentSize = size
+	}
+}
+
+// WithRelayCodec sets how items are written to disk, JSONCodec by default
+func WithRelayCodec[T any](codec Codec[Item[T]]) WithFileRelay[T] {
+	return func(s *FileRelayStore[T]) {
+		s.codec = codec
+	}
+}
+
+// WithSyncWrites fsyncs the segment after every Add, slower but nothing is lost on power failure
+func WithSyncWrites[T any](enabled bool) WithFileRelay[T] {
+	return func(s *FileRelayStore[T]) {
+		s.syncWrites = enabled
+	}
+}
+
+// FileRelayStore persists the relay in append-only segment files under dir and keeps
+// the last capacity items in memory. Segments only holding items that fell out of the
+// relay are deleted when a new segment is started (compaction).
+//
+// Each record is a 4 byte length, a 4 byte CRC32 and the encoded Item, a torn record at
+// the end of the last segment (crash during a write) is truncated on open.
+type FileRelayStore[T any] struct {
+	mu          *sync.Mutex
+	dir         string
+	capacity    int
+	segmentSize int64
+	syncWrites  bool
+	codec       Codec[Item[T]]
+	memory      *Relay[Item[T]]
+	segments    []segment // oldest first, the last one is active
+	active      *os.File
+	writer      *bufio.Writer
+}
+
+type segment struct {
+	path  string
+	size  int64
+	count int
+}
+
+// NewFileRelayStore opens or creates the store in dir and loads the last capacity items
+func NewFileRelayStore[T any](dir string, capacity int, opts ...WithFileRelay[T]) (*FileRelayStore[T], error) {
+	store := &FileRelayStore[T]{
+		mu:          &sync.Mutex{},
+		dir:         dir,
+		capacity:    max(capacity, 1),
+		segmentSize: 4 << 20,
+		codec:       JSONCodec[Item[T]](),
+	}
+
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	store.memory = NewRelay[Item[T]](store.capacity, 0)
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+
+	if len(store.segments) == 0 {
+		if err := store.rotate(1); err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+
+	if err := store.openActive(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (s *FileRelayStore[T]) Add(item Item[T]) error {
+	payload, err := s.codec.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("encode relay item: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return os.ErrClosed
+	}
+
+	current := &s.segments[len(s.segments)-1]
+	if current.size > 0 && current.size+recordSize(payload) > s.segmentSize {
+		if err := s.rotate(item.Seq); err != nil {
+			return err
+		}
+		current = &s.segments[len(s.segments)-1]
+	}
+
+	if err := s.write(payload); err != nil {
+		// bufio errors are sticky and a partial record would hide the next ones,
+		// cut the segment back to its last complete record
+		if truncErr := s.active.Truncate(current.size); truncErr != nil {
+			return errors.Join(err, truncErr)
+		}
+		s.writer.Reset(s.active)
+
+		return err
+	}
+
+	current.size += recordSize(payload)
+	current.count++
+	s.memory.Add(item)
+
+	return nil
+}
+
+func (s *FileRelayStore[T]) write(payload []byte) error {
+	if err := writeRecord(s.writer, payload); err != nil {
+		return err
+	}
+
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+
+	if s.syncWrites {
+		return s.active.Sync()
+	}
+
+	return nil
+}
+
+func (s *FileRelayStore[T]) Get() ([]Item[T], error) {
+	return s.memory.Get(), nil
+}
+
+func (s *FileRelayStore[T]) Len() int {
+	return s.memory.Len()
+}
+
+// Close flushes and closes the active segment, Add fails afterwards
+func (s *FileRelayStore[T]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return nil
+	}
+
+	err := errors.Join(s.writer.Flush(), s.active.Sync(), s.active.Close())
+	s.active, s.writer = nil, nil
+
+	return err
+}
+
+// rotate starts a new segment named after its first sequence, closes the previous one and compacts.
+// The new file is opened first so a failure keeps the current segment usable.
+func (s *FileRelayStore[T]) rotate(firstSeq uint64) error {
+	path := filepath.Join(s.dir, fmt.Sprintf("%020d%s", firstSeq, segmentExt))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if s.active != nil {
+		// every record already went through Flush in Add
+		if err := errors.Join(s.active.Sync(), s.active.Close()); err != nil {
+			log.Error().Err(err).Str("path", s.segments[len(s.segments)-1].path).Msg("Fail to close relay segment")
+		}
+	}
+
+	s.active, s.writer = file, bufio.NewWriter(file)
+	s.segments = append(s.segments, segment{path: path})
+	s.compact()
+
+	return nil
+}
+
+// compact deletes the oldest segments while the newer ones already hold capacity items
+func (s *FileRelayStore[T]) compact() {
+	total := 0
+	for idx := range s.segments {
+		total += s.segments[idx].count
+	}
+
+	for len(s.segments) > 1 && total-s.segments[0].count >= s.capacity {
+		if err := os.Remove(s.segments[0].path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Error().Err(err).Str("path", s.segments[0].path).Msg("Fail to remove relay segment")
+			return
+		}
+
+		total -= s.segments[0].count
+		s.segments = s.segments[1:]
+	}
+}
+
+func (s *FileRelayStore[T]) openActive() error {
+	current := s.segments[len(s.segments)-1]
+
+	file, err := os.OpenFile(current.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	// drop a torn record left by a crash so new records stay readable
+	if err := file.Truncate(current.size); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	s.active, s.writer = file, bufio.NewWriter(file)
+	return nil
+}
+
+func (s *FileRelayStore[T]) load() error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*"+segmentExt))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		seg, err := s.loadSegment(path)
+		if err != nil {
+			return err
+		}
+		s.segments = append(s.segments, seg)
+	}
+
+	return nil
+}
+
+// loadSegment reads every valid record of path, size is the offset of the last valid one
+func (s *FileRelayStore[T]) loadSegment(path string) (segment, error) {
+	seg := segment{path: path}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return seg, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		payload, err := readRecord(reader)
+		if errors.Is(err, io.EOF) {
+			return seg, nil
+		}
+		if err != nil {
+			log.Error().Err(err).Str("path", path).Int64("offset", seg.size).Msg("Ignore corrupted relay segment tail")
+			return seg, nil
+		}
+
+		item, err := s.codec.Unmarshal(payload)
+		if err != nil {
+			return seg, fmt.Errorf("decode relay item in %s: %w", path, err)
+		}
+
+		s.memory.Add(item)
+		seg.size += recordSize(payload)
+		seg.count++
+	}
+}
+
+func recordSize(payload []byte) int64 {
+	return int64(8 + len(payload))
+}
+
+func writeRecord(w io.Writer, payload []byte) error {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload))
+
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+
+	_, err := w.Write(payload)
+	return err
+}
+
+func readRecord(r io.Reader) ([]byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("torn record header: %w", err)
+		}
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:4])
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("record of %d bytes exceeds limit", size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("torn record: %w", err)
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, errors.New("record checksum mismatch")
+	}
+
+	return payload, nil
+}
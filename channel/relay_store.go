@@ -0,0 +1,37 @@
+package channel
+
+// RelayStore keeps the items replayed to new subscribers of a Fanout.
+// Items are added in sequence order, Get returns them oldest first.
+// A failing Add is logged and the item is still broadcast, only its replay is lost.
+type RelayStore[T any] interface {
+	Add(item Item[T]) error
+	Get() ([]Item[T], error)
+	Len() int
+}
+
+// WithRelayStore replaces the in-memory relay created by WithRelay, e.g. with a FileRelayStore
+// so history survives a restart. Sequence numbers resume after the last stored item.
+// Broker keeps using in-memory relays.
+func WithRelayStore[T any](store RelayStore[T]) WithOptions[T] {
+	return func(o *options[T]) {
+		o.relayStore = store
+	}
+}
+
+// memoryRelayStore is the default store, backed by a Relay ring buffer
+type memoryRelayStore[T any] struct {
+	relay *Relay[Item[T]]
+}
+
+func (m memoryRelayStore[T]) Add(item Item[T]) error {
+	m.relay.Add(item)
+	return nil
+}
+
+func (m memoryRelayStore[T]) Get() ([]Item[T], error) {
+	return m.relay.Get(), nil
+}
+
+func (m memoryRelayStore[T]) Len() int {
+	return m.relay.Len()
+}
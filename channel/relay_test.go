@@ -0,0 +1,29 @@
+package channel
+
+import (
+	"testing"
+	"time"
+)
+
+func BenchmarkRelayAdd(b *testing.B) {
+	relay := NewRelay[Item[int]](1024, 0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for idx := range b.N {
+		relay.Add(Item[int]{Seq: uint64(idx), Data: idx})
+	}
+}
+
+func BenchmarkRelayAddRetention(b *testing.B) {
+	// short enough for the head to expire while the benchmark runs
+	relay := NewRelay[Item[int]](1024, time.Microsecond)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for idx := range b.N {
+		relay.Add(Item[int]{Seq: uint64(idx), Data: idx})
+	}
+}
@@ -1,7 +1,155 @@
 package channel
 
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lamlv2305/toolkit/v2/fat"
+	"github.com/panjf2000/ants/v2"
+)
+
+// ErrSideEffectQueueFull is reported for the items a pooled side effect drops because it lags behind
+var ErrSideEffectQueueFull = errors.New("side effect queue full")
+
+// defaultSideEffectQueue is the number of items a pooled side effect can lag behind Send
+const defaultSideEffectQueue = 1024
+
 func WithSideEffect[T any](fn func(T)) WithOptions[T] {
 	return func(o *options[T]) {
 		o.sideEffect = append(o.sideEffect, fn)
 	}
 }
+
+// WithSideEffectPool runs side effects on pool (fat.DefaultPool when nil) instead of
+// a goroutine per item. Each side effect receives items one at a time in Send order,
+// different side effects run concurrently.
+func WithSideEffectPool[T any](pool *ants.Pool) WithOptions[T] {
+	return func(o *options[T]) {
+		if pool == nil {
+			pool = fat.DefaultPool()
+		}
+		o.sideEffectPool = pool
+	}
+}
+
+// WithSideEffectErrorHandler receives side effect panics and items dropped by a full queue
+func WithSideEffectErrorHandler[T any](fn func(data T, err error)) WithOptions[T] {
+	return func(o *options[T]) {
+		o.onSideEffectError = fn
+	}
+}
+
+// WithSideEffectQueue bounds how many items a pooled side effect can lag behind,
+// further items are dropped and reported with ErrSideEffectQueueFull. 1024 by default.
+func WithSideEffectQueue[T any](size int) WithOptions[T] {
+	return func(o *options[T]) {
+		o.sideEffectQueue = size
+	}
+}
+
+type sideEffects[T any] struct {
+	effects []*sideEffect[T]
+}
+
+func newSideEffects[T any](opts options[T]) *sideEffects[T] {
+	limit := opts.sideEffectQueue
+	if limit <= 0 {
+		limit = defaultSideEffectQueue
+	}
+
+	result := &sideEffects[T]{}
+	for idx := range opts.sideEffect {
+		result.effects = append(result.effects, &sideEffect[T]{
+			mu:      &sync.Mutex{},
+			index:   idx,
+			fn:      opts.sideEffect[idx],
+			pool:    opts.sideEffectPool,
+			limit:   limit,
+			onError: opts.onSideEffectError,
+		})
+	}
+
+	return result
+}
+
+func (s *sideEffects[T]) run(data T) {
+	for idx := range s.effects {
+		s.effects[idx].enqueue(data)
+	}
+}
+
+// sideEffect keeps at most one pool task per side effect, the task drains the
+// queue in order so a slow side effect never runs two items at once
+type sideEffect[T any] struct {
+	mu      *sync.Mutex
+	index   int
+	fn      func(T)
+	pool    *ants.Pool
+	limit   int
+	onError func(T, error)
+	queue   []T
+	running bool
+}
+
+func (s *sideEffect[T]) enqueue(data T) {
+	if s.pool == nil {
+		// show something like log here
+		go s.call(data)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) >= s.limit {
+		s.report(data, fmt.Errorf("side effect %d: %w", s.index, ErrSideEffectQueueFull))
+		return
+	}
+
+	s.queue = append(s.queue, data)
+	if s.running {
+		return
+	}
+	s.running = true
+
+	if err := s.pool.Submit(s.drain); err != nil {
+		// saturated or released pool, drain on a goroutine so queued items are not
+		// stuck until the next Send, still one drain at a time per side effect
+		go s.drain()
+	}
+}
+
+func (s *sideEffect[T]) drain() {
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.queue = nil
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+
+		data := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		s.call(data)
+	}
+}
+
+func (s *sideEffect[T]) call(data T) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.report(data, fmt.Errorf("side effect %d panic: %v", s.index, r))
+		}
+	}()
+
+	s.fn(data)
+}
+
+func (s *sideEffect[T]) report(data T, err error) {
+	if s.onError != nil {
+		s.onError(data, err)
+	}
+}
@@ -0,0 +1,35 @@
+package channel
+
+// Stats is a point in time snapshot of a Fanout or Broker
+type Stats struct {
+	Subscribers int
+	Queues      []QueueStats
+	Sent        uint64 // items that passed the middlewares since NewFanout/NewBroker, Seq may start higher
+	Filtered    uint64 // items rejected by a middleware
+	Dropped     uint64 // items not delivered to a subscriber because of its overflow policy
+	Relay       int    // items currently kept for replay
+}
+
+// QueueStats describes the buffer of one subscriber.
+// Len includes the relay history and live items still waiting to be replayed.
+type QueueStats struct {
+	ID     string
+	Len    int
+	Cap    int
+	Policy OverflowPolicy
+}
+
+// MetricsHook is notified on every event so counters can be exported to a metrics system,
+// calls happen on the Send / subscribe path and must not block
+type MetricsHook interface {
+	OnSent(seq uint64)
+	OnFiltered()
+	OnDropped(policy OverflowPolicy)
+	OnSubscribers(count int)
+}
+
+func WithMetrics[T any](hook MetricsHook) WithOptions[T] {
+	return func(o *options[T]) {
+		o.metrics = hook
+	}
+}
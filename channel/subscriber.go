@@ -0,0 +1,288 @@
+package channel
+
+import (
+	"sync"
+	"time"
+)
+
+type deliveryResult int
+
+const (
+	delivered deliveryResult = iota
+	dropped
+	disconnected
+	unsubscribed
+)
+
+// sink is the type erased side of a subscriber, so plain and Item channels share one registry
+type sink[T any] interface {
+	accepts(data T) bool
+	policy() OverflowPolicy
+	depth() (int, int)
+	startReplay(history []Item[T], last uint64) bool
+	push(item Item[T], onDrop func(T)) deliveryResult
+	next() (Item[T], bool)
+	send(item Item[T], onDrop func(T)) deliveryResult
+	close()
+}
+
+// subscriber owns a single consumer channel.
+// Every send and the final close happen under mu so the channel is never
+// written after it has been closed, done is closed first to wake up a
+// sender that is blocked on a full buffer.
+//
+// While the relay history is replayed, live items are queued in pending
+// (bounded by the channel buffer) so the consumer sees history then live
+// data in sequence order.
+type subscriber[T, O any] struct {
+	mu      *sync.Mutex
+	ch      chan O
+	wrap    func(Item[T]) O
+	unwrap  func(O) T
+	done    chan struct{}
+	once    *sync.Once
+	closed  bool
+	ignore  func(T) bool
+	options subscriberOptions
+
+	backlog   *sync.Mutex
+	last      uint64 // seq when it was registered, older live items are already in history
+	history   []Item[T]
+	pending   []Item[T]
+	replaying bool
+	progress  chan struct{}
+}
+
+func newSubscriber[T, O any](buffer int, wrap func(Item[T]) O, unwrap func(O) T, ignore func(T) bool, opts subscriberOptions) *subscriber[T, O] {
+	return &subscriber[T, O]{
+		mu:       &sync.Mutex{},
+		ch:       make(chan O, buffer),
+		wrap:     wrap,
+		unwrap:   unwrap,
+		done:     make(chan struct{}),
+		once:     &sync.Once{},
+		ignore:   ignore,
+		options:  opts,
+		backlog:  &sync.Mutex{},
+		progress: make(chan struct{}, 1),
+	}
+}
+
+func (s *subscriber[T, O]) accepts(data T) bool {
+	return s.ignore == nil || !s.ignore(data)
+}
+
+func (s *subscriber[T, O]) policy() OverflowPolicy {
+	return s.options.overflow
+}
+
+// depth returns the number of queued items and the channel capacity
+func (s *subscriber[T, O]) depth() (int, int) {
+	s.backlog.Lock()
+	queued := len(s.history) + len(s.pending)
+	s.backlog.Unlock()
+
+	return len(s.ch) + queued, cap(s.ch)
+}
+
+// startReplay must be called before the subscriber is visible to Send, last is the
+// seq of the most recent item at that point
+func (s *subscriber[T, O]) startReplay(history []Item[T], last uint64) bool {
+	s.last = last
+	for idx := range history {
+		if s.accepts(history[idx].Data) {
+			s.history = append(s.history, history[idx])
+		}
+	}
+
+	s.replaying = len(s.history) > 0
+	return s.replaying
+}
+
+// next pops the next item to replay, history first then live items queued meanwhile.
+// Once it returns false the subscriber switches to direct delivery.
+func (s *subscriber[T, O]) next() (Item[T], bool) {
+	s.backlog.Lock()
+	defer s.backlog.Unlock()
+
+	var item Item[T]
+	switch {
+	case len(s.history) > 0:
+		item, s.history = s.history[0], s.history[1:]
+	case len(s.pending) > 0:
+		item, s.pending = s.pending[0], s.pending[1:]
+	default:
+		s.history, s.pending = nil, nil
+		s.replaying = false
+		return item, false
+	}
+
+	select {
+	case s.progress <- struct{}{}:
+	default:
+	}
+
+	return item, true
+}
+
+// replay delivers the backlog of sub until it switches to direct delivery,
+// handle is called with every result but unsubscribed
+func replay[T any](sub sink[T], onDrop func(T), handle func(Item[T], deliveryResult)) {
+	for {
+		item, ok := sub.next()
+		if !ok {
+			return
+		}
+
+		result := sub.send(item, onDrop)
+		if result == unsubscribed {
+			return
+		}
+
+		handle(item, result)
+		if result == disconnected {
+			return
+		}
+	}
+}
+
+// push delivers a live item, queueing it behind the relay history while it is being replayed
+func (s *subscriber[T, O]) push(item Item[T], onDrop func(T)) deliveryResult {
+	// a Send that was in flight while registering, covered by the history (or older than the subscription)
+	if item.Seq <= s.last {
+		return delivered
+	}
+
+	var timeout <-chan time.Time
+	if s.options.overflow == OverflowDisconnect && s.options.timeout > 0 {
+		timer := time.NewTimer(s.options.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		s.backlog.Lock()
+		if !s.replaying {
+			s.backlog.Unlock()
+			return s.send(item, onDrop)
+		}
+
+		if len(s.pending) < max(cap(s.ch), 1) {
+			s.pending = append(s.pending, item)
+			s.backlog.Unlock()
+			return delivered
+		}
+
+		switch s.options.overflow {
+		case OverflowDropNewest:
+			s.backlog.Unlock()
+			return dropped
+
+		case OverflowDropOldest:
+			onDrop(s.pending[0].Data)
+			s.pending = append(s.pending[1:], item)
+			s.backlog.Unlock()
+			return delivered
+
+		case OverflowDisconnect:
+			if timeout == nil {
+				s.backlog.Unlock()
+				return disconnected
+			}
+		}
+		s.backlog.Unlock()
+
+		select {
+		case <-s.progress:
+		case <-s.done:
+			return unsubscribed
+		case <-timeout:
+			return disconnected
+		}
+	}
+}
+
+// send pushes data to the channel following the overflow policy, onDrop receives every item
+// that has been discarded to make room (drop oldest)
+func (s *subscriber[T, O]) send(item Item[T], onDrop func(T)) deliveryResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return unsubscribed
+	}
+
+	data := s.wrap(item)
+
+	switch s.options.overflow {
+	case OverflowDropNewest:
+		select {
+		case s.ch <- data:
+			return delivered
+		default:
+			return dropped
+		}
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case s.ch <- data:
+				return delivered
+			default:
+			}
+
+			// make room by discarding the oldest buffered item,
+			// the consumer may have read it in the meantime so just retry
+			select {
+			case old := <-s.ch:
+				onDrop(s.unwrap(old))
+			default:
+			}
+		}
+
+	case OverflowDisconnect:
+		if s.options.timeout <= 0 {
+			select {
+			case s.ch <- data:
+				return delivered
+			default:
+				return disconnected
+			}
+		}
+
+		timer := time.NewTimer(s.options.timeout)
+		defer timer.Stop()
+
+		select {
+		case s.ch <- data:
+			return delivered
+		case <-s.done:
+			return unsubscribed
+		case <-timer.C:
+			return disconnected
+		}
+
+	default:
+		select {
+		case s.ch <- data:
+			return delivered
+		case <-s.done:
+			return unsubscribed
+		}
+	}
+}
+
+// close is idempotent and safe to call concurrently with send
+func (s *subscriber[T, O]) close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
@@ -0,0 +1,106 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTransportClosed is returned by a Transport once Close has been called
+var ErrTransportClosed = errors.New("transport closed")
+
+// Transport moves opaque messages between nodes.
+// Publish must deliver to every other node, delivering back to the publisher is allowed
+// (Bridge ignores its own messages). Receive is called from a single goroutine.
+type Transport interface {
+	Publish(ctx context.Context, payload []byte) error
+	Receive(ctx context.Context) ([]byte, error)
+	Close() error
+}
+
+// MemoryNetwork connects in-process transports, useful for tests and single binary setups
+type MemoryNetwork struct {
+	mu      *sync.RWMutex
+	members map[*memoryTransport]struct{}
+}
+
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{
+		mu:      &sync.RWMutex{},
+		members: map[*memoryTransport]struct{}{},
+	}
+}
+
+// Join adds a node to the network, buffer is the number of messages it can hold before Publish blocks
+func (n *MemoryNetwork) Join(buffer int) Transport {
+	member := &memoryTransport{
+		network: n,
+		ch:      make(chan []byte, buffer),
+		done:    make(chan struct{}),
+		once:    &sync.Once{},
+	}
+
+	n.mu.Lock()
+	n.members[member] = struct{}{}
+	n.mu.Unlock()
+
+	return member
+}
+
+type memoryTransport struct {
+	network *MemoryNetwork
+	ch      chan []byte
+	done    chan struct{}
+	once    *sync.Once
+}
+
+func (m *memoryTransport) Publish(ctx context.Context, payload []byte) error {
+	select {
+	case <-m.done:
+		return ErrTransportClosed
+	default:
+	}
+
+	m.network.mu.RLock()
+	members := make([]*memoryTransport, 0, len(m.network.members))
+	for member := range m.network.members {
+		if member != m {
+			members = append(members, member)
+		}
+	}
+	m.network.mu.RUnlock()
+
+	for _, member := range members {
+		select {
+		case member.ch <- payload:
+		case <-member.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryTransport) Receive(ctx context.Context) ([]byte, error) {
+	select {
+	case payload := <-m.ch:
+		return payload, nil
+	case <-m.done:
+		return nil, ErrTransportClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (m *memoryTransport) Close() error {
+	m.once.Do(func() {
+		m.network.mu.Lock()
+		delete(m.network.members, m)
+		m.network.mu.Unlock()
+
+		close(m.done)
+	})
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package channel
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxFrameSize guards against reading a corrupted length prefix
+const maxFrameSize = 16 << 20
+
+// TCPHub relays every frame received from one connection to all the others.
+// It has no persistence nor authentication, it is meant for tests and small
+// deployments where running an external broker is not worth it.
+type TCPHub struct {
+	ln     net.Listener
+	mu     *sync.Mutex
+	conns  map[net.Conn]*sync.Mutex // value serializes writes to the conn
+	closed bool                     // set under mu, late connections are refused
+	wg     *sync.WaitGroup
+}
+
+// ListenTCP starts a hub on addr, use "127.0.0.1:0" to pick a free loopback port
+func ListenTCP(addr string) (*TCPHub, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	hub := &TCPHub{
+		ln:    ln,
+		mu:    &sync.Mutex{},
+		conns: map[net.Conn]*sync.Mutex{},
+		wg:    &sync.WaitGroup{},
+	}
+
+	hub.wg.Add(1)
+	go hub.accept()
+
+	return hub, nil
+}
+
+// Addr returns the address transports should dial
+func (h *TCPHub) Addr() string {
+	return h.ln.Addr().String()
+}
+
+// Close stops accepting, disconnects every node and waits for the relay goroutines
+func (h *TCPHub) Close() error {
+	err := h.ln.Close()
+
+	h.mu.Lock()
+	h.closed = true
+	for conn := range h.conns {
+		_ = conn.Close()
+	}
+	h.mu.Unlock()
+
+	h.wg.Wait()
+	return err
+}
+
+func (h *TCPHub) accept() {
+	defer h.wg.Done()
+
+	for {
+		conn, err := h.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		h.mu.Lock()
+		if h.closed {
+			// accepted right before Close, its relay would block Close
+			h.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		h.conns[conn] = &sync.Mutex{}
+		h.mu.Unlock()
+
+		h.wg.Add(1)
+		go h.relay(conn)
+	}
+}
+
+func (h *TCPHub) relay(conn net.Conn) {
+	defer h.wg.Done()
+	defer func() {
+		h.mu.Lock()
+		delete(h.conns, conn)
+		h.mu.Unlock()
+
+		_ = conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	for {
+		payload, err := readFrame(reader)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Error().Err(err).Str("remote", conn.RemoteAddr().String()).Msg("Fail to read tcp hub frame")
+			}
+			return
+		}
+
+		h.mu.Lock()
+		targets := make(map[net.Conn]*sync.Mutex, len(h.conns))
+		for other, wmu := range h.conns {
+			if other != conn {
+				targets[other] = wmu
+			}
+		}
+		h.mu.Unlock()
+
+		for other, wmu := range targets {
+			wmu.Lock()
+			err := writeFrame(other, payload)
+			wmu.Unlock()
+
+			if err != nil {
+				// the relay goroutine of that conn cleans it up
+				_ = other.Close()
+			}
+		}
+	}
+}
+
+// DialTCP connects a transport to a TCPHub
+func DialTCP(ctx context.Context, addr string) (Transport, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := &tcpTransport{
+		conn:     conn,
+		wmu:      &sync.Mutex{},
+		messages: make(chan []byte),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+		once:     &sync.Once{},
+	}
+
+	go transport.read()
+
+	return transport, nil
+}
+
+type tcpTransport struct {
+	conn     net.Conn
+	wmu      *sync.Mutex
+	messages chan []byte
+	closing  chan struct{}
+	done     chan struct{}
+	once     *sync.Once
+	err      error // read error, set before done is closed
+}
+
+func (t *tcpTransport) Publish(ctx context.Context, payload []byte) error {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+
+	select {
+	case <-t.done:
+		return ErrTransportClosed
+	default:
+	}
+
+	// zero deadline when ctx has none, which clears a previous one
+	deadline, _ := ctx.Deadline()
+	if err := t.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+
+	return writeFrame(t.conn, payload)
+}
+
+func (t *tcpTransport) Receive(ctx context.Context) ([]byte, error) {
+	select {
+	case payload := <-t.messages:
+		return payload, nil
+	case <-t.done:
+		return nil, t.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *tcpTransport) Close() error {
+	var err error
+	t.once.Do(func() {
+		close(t.closing)
+		err = t.conn.Close()
+	})
+
+	return err
+}
+
+func (t *tcpTransport) read() {
+	defer close(t.done)
+
+	reader := bufio.NewReader(t.conn)
+	for {
+		payload, err := readFrame(reader)
+		if err != nil {
+			t.err = ErrTransportClosed
+			if !errors.Is(err, net.ErrClosed) {
+				t.err = fmt.Errorf("%w: %w", ErrTransportClosed, err)
+			}
+			return
+		}
+
+		select {
+		case t.messages <- payload:
+		case <-t.closing:
+			t.err = ErrTransportClosed
+			return
+		}
+	}
+}
+
+// frames are a 4 byte big endian length followed by the payload
+func writeFrame(w io.Writer, payload []byte) error {
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[4:], payload)
+
+	_, err := w.Write(frame)
+	return err
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("frame of %d bytes exceeds limit", size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
@@ -2,15 +2,20 @@ package fat
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/panjf2000/ants/v2"
 )
 
 type AsyncOption struct {
-	ctx     context.Context
-	pool    *ants.Pool
-	timeout time.Duration
+	ctx        context.Context
+	pool       *ants.Pool
+	timeout    time.Duration
+	onAbandon  func(overrun time.Duration)
+	abandonAt  time.Duration
+	saturation SaturationPolicy
+	backlog    int
 }
 
 type AsyncOptionFunc func(*AsyncOption)
@@ -27,42 +32,83 @@ func WithPool(pool *ants.Pool) AsyncOptionFunc {
 	}
 }
 
+// WithTimeout cancels the task context once the task has run for timeout,
+// the time spent waiting for a worker does not count
 func WithTimeout(timeout time.Duration) AsyncOptionFunc {
 	return func(opt *AsyncOption) {
 		opt.timeout = timeout
 	}
 }
 
-// AsyncExec executes a function asynchronously using ants pool
-func AsyncExec(fn func(ctx context.Context) error, opts ...AsyncOptionFunc) chan error {
+// WithAbandonHandler is called when a function ignored the cancellation of its context
+// and returned later anyway, with how long it kept running after the context ended
+func WithAbandonHandler(fn func(overrun time.Duration)) AsyncOptionFunc {
+	return func(opt *AsyncOption) {
+		opt.onAbandon = fn
+	}
+}
+
+// WithAbandonAfter is how long a function may keep running once its context ended
+// before it counts as abandoned, 10ms by default
+func WithAbandonAfter(threshold time.Duration) AsyncOptionFunc {
+	return func(opt *AsyncOption) {
+		opt.abandonAt = threshold
+	}
+}
+
+// abandoned counts functions that ignored their context, AsyncExec already returned ctx.Err()
+var abandoned atomic.Int64
+
+// Abandoned returns how many functions are still running well after their context ended, see WithAbandonAfter
+func Abandoned() int64 {
+	return abandoned.Load()
+}
+
+func newAsyncOption(opts []AsyncOptionFunc) *AsyncOption {
 	options := &AsyncOption{
-		ctx:  context.Background(),
-		pool: getDefaultPool(),
+		ctx:       context.Background(),
+		pool:      getDefaultPool(),
+		abandonAt: 10 * time.Millisecond,
 	}
 
 	for _, opt := range opts {
 		opt(options)
 	}
 
-	// Apply timeout if specified
-	if options.timeout > 0 {
-		ctx, cancel := context.WithTimeout(options.ctx, options.timeout)
-		defer cancel()
-		options.ctx = ctx
-	}
+	return options
+}
+
+// AsyncExec executes a function asynchronously using ants pool
+func AsyncExec(fn func(ctx context.Context) error, opts ...AsyncOptionFunc) chan error {
+	options := newAsyncOption(opts)
 
 	errCh := make(chan error, 1)
+	queued := time.Now()
 
 	// Submit task to ants pool
-	err := options.pool.Submit(func() {
+	submit(options, func() {
 		defer close(errCh)
 
+		// Timeout starts with the task, not when it is queued,
+		// and fn sees its context cancelled as soon as the task is over
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if options.timeout > 0 {
+			ctx, cancel = context.WithTimeout(options.ctx, options.timeout)
+		} else {
+			ctx, cancel = context.WithCancel(options.ctx)
+		}
+		defer cancel()
+
 		// Create a done channel for the function execution
 		done := make(chan error, 1)
 
 		// Execute function in a separate goroutine to handle context cancellation
 		go func() {
-			done <- fn(options.ctx)
+			// a panic is recovered and delivered as a *PanicError
+			done <- execute(queued, func() error {
+				return fn(ctx)
+			})
 		}()
 
 		// Wait for either completion or context cancellation
@@ -71,21 +117,44 @@ func AsyncExec(fn func(ctx context.Context) error, opts ...AsyncOptionFunc) chan
 			if err != nil {
 				errCh <- err
 			}
-		case <-options.ctx.Done():
-			errCh <- options.ctx.Err()
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			go trackAbandoned(done, options.abandonAt, options.onAbandon)
 		}
+	}, func(err error) {
+		// the pool refused the task, now or once it left the backlog
+		rejected(queued, err)
+		errCh <- err
+		close(errCh)
 	})
-	// If pool submission fails, handle synchronously
-	if err != nil {
-		go func() {
-			defer close(errCh)
-			errCh <- err
-		}()
-	}
 
 	return errCh
 }
 
+// trackAbandoned keeps count of a function that outlives its context by more than threshold until it returns
+func trackAbandoned(done chan error, threshold time.Duration, onAbandon func(overrun time.Duration)) {
+	start := time.Now()
+
+	timer := time.NewTimer(threshold)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		// honored the cancellation
+		return
+	case <-timer.C:
+	}
+
+	abandoned.Add(1)
+	defer abandoned.Add(-1)
+
+	<-done
+
+	if onAbandon != nil {
+		onAbandon(time.Since(start))
+	}
+}
+
 // Cleanup releases the default pool (call on shutdown)
 func Cleanup() {
 	if defaultPool != nil {
@@ -0,0 +1,58 @@
+package fat
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAsyncExecCooperativeNotAbandoned(t *testing.T) {
+	var reported atomic.Int32
+
+	for range 20 {
+		err := <-AsyncExec(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, WithTimeout(time.Millisecond), WithAbandonHandler(func(time.Duration) {
+			reported.Add(1)
+		}))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v", err)
+		}
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if reported.Load() != 0 {
+		t.Fatalf("%d cooperative functions reported as abandoned", reported.Load())
+	}
+}
+
+func TestAsyncExecAbandoned(t *testing.T) {
+	release := make(chan struct{})
+	overrun := make(chan time.Duration, 1)
+
+	err := <-AsyncExec(func(ctx context.Context) error {
+		<-release
+		return nil
+	}, WithTimeout(time.Millisecond), WithAbandonAfter(5*time.Millisecond), WithAbandonHandler(func(d time.Duration) {
+		overrun <- d
+	}))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for Abandoned() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("stubborn function not counted as abandoned")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	if d := <-overrun; d < 5*time.Millisecond {
+		t.Fatalf("overrun %v shorter than the threshold", d)
+	}
+}
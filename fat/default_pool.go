@@ -10,13 +10,43 @@ import (
 var (
 	defaultPool *ants.Pool
 	poolOnce    sync.Once
+
+	defaultPoolMu     sync.Mutex
+	defaultPoolSize   = 100
+	defaultPoolExpiry = 30 * time.Second
 )
 
+// ConfigureDefaultPool sets the size and worker expiry of the default pool.
+// The size also applies to an already running pool, the expiry only before its first use.
+func ConfigureDefaultPool(size int, expiry time.Duration) {
+	defaultPoolMu.Lock()
+	defer defaultPoolMu.Unlock()
+
+	if size > 0 {
+		defaultPoolSize = size
+		if defaultPool != nil {
+			defaultPool.Tune(size)
+		}
+	}
+
+	if expiry > 0 {
+		defaultPoolExpiry = expiry
+	}
+}
+
+// DefaultPool returns the pool used when no WithPool option is given
+func DefaultPool() *ants.Pool {
+	return getDefaultPool()
+}
+
 func getDefaultPool() *ants.Pool {
 	poolOnce.Do(func() {
+		defaultPoolMu.Lock()
+		defer defaultPoolMu.Unlock()
+
 		var err error
-		defaultPool, err = ants.NewPool(100,
-			ants.WithExpiryDuration(30*time.Second),
+		defaultPool, err = ants.NewPool(defaultPoolSize,
+			ants.WithExpiryDuration(defaultPoolExpiry),
 			ants.WithPreAlloc(false),
 			ants.WithNonblocking(true),
 		)
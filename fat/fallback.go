@@ -1,12 +1,20 @@
 package fat
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 var ErrBreakFallback = errors.New("break fallback")
 
+var errEmptyFallback = errors.New("empty conditions")
+
 func Fallback[T any](args ...func() (*T, error)) (*T, error) {
 	if len(args) == 0 {
-		return nil, errors.New("empty conditions")
+		return nil, errEmptyFallback
 	}
 
 	rest := args[:len(args)-1]
@@ -26,3 +34,154 @@ func Fallback[T any](args ...func() (*T, error)) (*T, error) {
 
 	return last()
 }
+
+// AttemptError is the failure of the attempt at index Attempt of the arguments
+type AttemptError struct {
+	Attempt int
+	Err     error
+}
+
+func (e AttemptError) Error() string {
+	return fmt.Sprintf("attempt %d: %v", e.Attempt, e.Err)
+}
+
+func (e AttemptError) Unwrap() error {
+	return e.Err
+}
+
+// FallbackError lists the failed attempts in attempt order, Cause is set when ctx ended the run.
+// errors.Is matches any of them, e.g. ErrBreakFallback or context.DeadlineExceeded.
+type FallbackError struct {
+	Attempts []AttemptError
+	Cause    error
+}
+
+func (e *FallbackError) Error() string {
+	var b strings.Builder
+	b.WriteString("fallback failed")
+	for _, attempt := range e.Attempts {
+		fmt.Fprintf(&b, "; %v", attempt)
+	}
+	if e.Cause != nil {
+		fmt.Fprintf(&b, "; %v", e.Cause)
+	}
+	return b.String()
+}
+
+func (e *FallbackError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Attempts)+1)
+	for _, attempt := range e.Attempts {
+		errs = append(errs, attempt)
+	}
+	if e.Cause != nil {
+		errs = append(errs, e.Cause)
+	}
+	return errs
+}
+
+type fallbackOptions struct {
+	timeout   time.Duration
+	hedge     time.Duration
+	onFailure func(attempt int, err error)
+}
+
+type FallbackOption func(*fallbackOptions)
+
+// WithAttemptTimeout bounds every attempt, the function has to honor its context
+func WithAttemptTimeout(timeout time.Duration) FallbackOption {
+	return func(o *fallbackOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithFailureHandler is called after each failed attempt, before the next one starts
+func WithFailureHandler(fn func(attempt int, err error)) FallbackOption {
+	return func(o *fallbackOptions) {
+		o.onFailure = fn
+	}
+}
+
+// WithHedge starts the next attempt when the running ones have not answered after delay,
+// or right away when one fails. The first success wins and the others are cancelled.
+func WithHedge(delay time.Duration) FallbackOption {
+	return func(o *fallbackOptions) {
+		o.hedge = delay
+	}
+}
+
+// FallbackRunner runs fallbacks with the same options, see NewFallback
+type FallbackRunner[T any] struct {
+	options fallbackOptions
+}
+
+func NewFallback[T any](opts ...FallbackOption) FallbackRunner[T] {
+	runner := FallbackRunner[T]{}
+	for _, opt := range opts {
+		opt(&runner.options)
+	}
+
+	return runner
+}
+
+// FallbackCtx is Fallback with a context, see FallbackRunner.Run
+func FallbackCtx[T any](ctx context.Context, args ...func(ctx context.Context) (*T, error)) (*T, error) {
+	return NewFallback[T]().Run(ctx, args...)
+}
+
+// Run tries args in order and returns the first non nil result.
+// A nil result without error counts as a failure, so does a panic (*PanicError). An error wrapping ErrBreakFallback
+// stops the chain. When every attempt failed it returns a *FallbackError.
+func (f FallbackRunner[T]) Run(ctx context.Context, args ...func(ctx context.Context) (*T, error)) (*T, error) {
+	if len(args) == 0 {
+		return nil, errEmptyFallback
+	}
+
+	if f.options.hedge > 0 {
+		return f.hedged(ctx, args)
+	}
+
+	result := &FallbackError{}
+	for idx := range args {
+		if err := ctx.Err(); err != nil {
+			result.Cause = err
+			break
+		}
+
+		value, err := f.attempt(ctx, time.Now(), args[idx])
+		if err == nil {
+			return value, nil
+		}
+
+		result.Attempts = append(result.Attempts, AttemptError{Attempt: idx, Err: err})
+		if f.options.onFailure != nil {
+			f.options.onFailure(idx, err)
+		}
+
+		if errors.Is(err, ErrBreakFallback) {
+			break
+		}
+	}
+
+	return nil, result
+}
+
+// attempt runs fn through execute, a panic fails the attempt with a *PanicError and the
+// task observer sees every attempt, queued is when the attempt was scheduled
+func (f FallbackRunner[T]) attempt(ctx context.Context, queued time.Time, fn func(ctx context.Context) (*T, error)) (*T, error) {
+	if f.options.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.options.timeout)
+		defer cancel()
+	}
+
+	var result *T
+	err := execute(queued, func() (err error) {
+		result, err = fn(ctx)
+		if err == nil && result == nil {
+			err = errors.New("nil result")
+		}
+		return err
+	})
+
+	return result, err
+}
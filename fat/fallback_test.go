@@ -0,0 +1,100 @@
+package fat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func value(n int) *int {
+	return &n
+}
+
+func TestFallbackCtx(t *testing.T) {
+	var failed []int
+	runner := NewFallback[int](
+		WithAttemptTimeout(10*time.Millisecond),
+		WithFailureHandler(func(attempt int, err error) {
+			failed = append(failed, attempt)
+		}),
+	)
+
+	result, err := runner.Run(context.Background(),
+		func(ctx context.Context) (*int, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		func(context.Context) (*int, error) { return nil, nil },
+		func(context.Context) (*int, error) { panic("boom") },
+		func(context.Context) (*int, error) { return value(4), nil },
+	)
+	if err != nil || *result != 4 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+	if fmt.Sprint(failed) != "[0 1 2]" {
+		t.Fatalf("failure handler saw %v", failed)
+	}
+}
+
+func TestFallbackCtxBreak(t *testing.T) {
+	_, err := FallbackCtx(context.Background(),
+		func(context.Context) (*int, error) { return nil, errors.New("down") },
+		func(context.Context) (*int, error) { return nil, fmt.Errorf("auth: %w", ErrBreakFallback) },
+		func(context.Context) (*int, error) { return value(3), nil },
+	)
+
+	var fallbackErr *FallbackError
+	if !errors.As(err, &fallbackErr) || !errors.Is(err, ErrBreakFallback) {
+		t.Fatalf("got %v", err)
+	}
+	if want := "fallback failed; attempt 0: down; attempt 1: auth: break fallback"; err.Error() != want {
+		t.Fatalf("got %q, want %q", err.Error(), want)
+	}
+}
+
+func TestFallbackHedge(t *testing.T) {
+	cancelled := make(chan struct{})
+	runner := NewFallback[int](WithHedge(5 * time.Millisecond))
+
+	start := time.Now()
+	result, err := runner.Run(context.Background(),
+		func(ctx context.Context) (*int, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		},
+		func(context.Context) (*int, error) { return value(2), nil },
+	)
+	if err != nil || *result != 2 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("hedged run took %v", elapsed)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("losing attempt not cancelled")
+	}
+}
+
+func TestFallbackHedgeAttemptIndex(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	runner := NewFallback[int](WithHedge(time.Millisecond))
+	_, err := runner.Run(context.Background(),
+		func(context.Context) (*int, error) {
+			<-release
+			return nil, errors.New("slow")
+		},
+		func(context.Context) (*int, error) { return nil, ErrBreakFallback },
+	)
+
+	if want := "fallback failed; attempt 1: break fallback"; err == nil || err.Error() != want {
+		t.Fatalf("got %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,192 @@
+package fat
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Future is the result of a task submitted with Go
+type Future[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+func newFuture[T any]() *Future[T] {
+	return &Future[T]{done: make(chan struct{})}
+}
+
+// Go runs fn on the ants pool and returns its Future, a panic in fn resolves it with a *PanicError.
+// WithTimeout applies to fn once it starts running, not to the time spent waiting for a worker.
+func Go[T any](fn func(ctx context.Context) (T, error), opts ...AsyncOptionFunc) *Future[T] {
+	options := newAsyncOption(opts)
+	future := newFuture[T]()
+	queued := time.Now()
+
+	submit(options, func() {
+		ctx := options.ctx
+		if options.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, options.timeout)
+			defer cancel()
+		}
+
+		var value T
+		err := execute(queued, func() (err error) {
+			value, err = fn(ctx)
+			return err
+		})
+
+		future.complete(value, err)
+	}, func(err error) {
+		rejected(queued, err)
+		var zero T
+		future.complete(zero, err)
+	})
+
+	return future
+}
+
+func (f *Future[T]) complete(value T, err error) {
+	f.value, f.err = value, err
+	close(f.done)
+}
+
+// Done is closed once the result is available
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Await waits for the result or ctx, giving up on ctx does not cancel the task
+func (f *Future[T]) Await(ctx context.Context) (T, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Then runs fn on the pool with the result of f, an error of f is passed through without calling fn
+func Then[T, R any](f *Future[T], fn func(ctx context.Context, value T) (R, error), opts ...AsyncOptionFunc) *Future[R] {
+	result := newFuture[R]()
+
+	go func() {
+		<-f.done
+		if f.err != nil {
+			var zero R
+			result.complete(zero, f.err)
+			return
+		}
+
+		next := Go(func(ctx context.Context) (R, error) {
+			return fn(ctx, f.value)
+		}, opts...)
+
+		<-next.done
+		result.complete(next.value, next.err)
+	}()
+
+	return result
+}
+
+// All resolves with every value in order, or with the first error
+func All[T any](futures ...*Future[T]) *Future[[]T] {
+	result := newFuture[[]T]()
+
+	go func() {
+		failed := make(chan error, len(futures))
+		for _, future := range futures {
+			go func() {
+				<-future.done
+				if future.err != nil {
+					failed <- future.err
+				}
+			}()
+		}
+
+		for _, future := range futures {
+			select {
+			case <-future.done:
+			case err := <-failed:
+				result.complete(nil, err)
+				return
+			}
+		}
+
+		values := make([]T, len(futures))
+		for idx, future := range futures {
+			if future.err != nil {
+				result.complete(nil, future.err)
+				return
+			}
+			values[idx] = future.value
+		}
+
+		result.complete(values, nil)
+	}()
+
+	return result
+}
+
+// Any resolves with the first successful value, or with every error joined when all fail
+func Any[T any](futures ...*Future[T]) *Future[T] {
+	result := newFuture[T]()
+
+	go func() {
+		settled := make(chan *Future[T], len(futures))
+		for _, future := range futures {
+			go func() {
+				<-future.done
+				settled <- future
+			}()
+		}
+
+		errs := make([]error, 0, len(futures))
+		for range futures {
+			future := <-settled
+			if future.err == nil {
+				result.complete(future.value, nil)
+				return
+			}
+			errs = append(errs, future.err)
+		}
+
+		var zero T
+		if len(errs) == 0 {
+			result.complete(zero, errors.New("no futures"))
+			return
+		}
+		result.complete(zero, errors.Join(errs...))
+	}()
+
+	return result
+}
+
+// Race resolves with whichever future settles first, success or error
+func Race[T any](futures ...*Future[T]) *Future[T] {
+	result := newFuture[T]()
+
+	go func() {
+		settled := make(chan *Future[T], len(futures))
+		for _, future := range futures {
+			go func() {
+				<-future.done
+				settled <- future
+			}()
+		}
+
+		if len(futures) == 0 {
+			var zero T
+			result.complete(zero, errors.New("no futures"))
+			return
+		}
+
+		future := <-settled
+		result.complete(future.value, future.err)
+	}()
+
+	return result
+}
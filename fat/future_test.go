@@ -0,0 +1,101 @@
+package fat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFutureThen(t *testing.T) {
+	doubled := Then(Go(func(context.Context) (int, error) {
+		return 21, nil
+	}), func(_ context.Context, value int) (int, error) {
+		return value * 2, nil
+	})
+
+	value, err := doubled.Await(context.Background())
+	if err != nil || value != 42 {
+		t.Fatalf("got %d, %v", value, err)
+	}
+}
+
+func TestFuturePanic(t *testing.T) {
+	_, err := Go(func(context.Context) (int, error) {
+		panic("boom")
+	}).Await(context.Background())
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestFutureAll(t *testing.T) {
+	futures := make([]*Future[int], 0, 5)
+	for idx := range 5 {
+		futures = append(futures, Go(func(context.Context) (int, error) {
+			time.Sleep(time.Duration(5-idx) * time.Millisecond)
+			return idx, nil
+		}))
+	}
+
+	values, err := All(futures...).Await(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx, value := range values {
+		if value != idx {
+			t.Fatalf("got %v, want input order", values)
+		}
+	}
+
+	errFailed := errors.New("failed")
+	slow := Go(func(ctx context.Context) (int, error) {
+		time.Sleep(time.Second)
+		return 0, nil
+	})
+	failing := Go(func(context.Context) (int, error) { return 0, errFailed })
+
+	start := time.Now()
+	if _, err := All(slow, failing).Await(context.Background()); !errors.Is(err, errFailed) {
+		t.Fatalf("got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("All waited for the slow future after a failure")
+	}
+}
+
+func TestFutureAnyRace(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+
+	value, err := Any(
+		Go(func(context.Context) (int, error) { return 0, errA }),
+		Go(func(context.Context) (int, error) {
+			time.Sleep(5 * time.Millisecond)
+			return 7, nil
+		}),
+	).Await(context.Background())
+	if err != nil || value != 7 {
+		t.Fatalf("got %d, %v", value, err)
+	}
+
+	_, err = Any(
+		Go(func(context.Context) (int, error) { return 0, errA }),
+		Go(func(context.Context) (int, error) { return 0, errB }),
+	).Await(context.Background())
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Fatalf("got %v", err)
+	}
+
+	_, err = Race(
+		Go(func(context.Context) (int, error) { return 0, errA }),
+		Go(func(context.Context) (int, error) {
+			time.Sleep(50 * time.Millisecond)
+			return 1, nil
+		}),
+	).Await(context.Background())
+	if !errors.Is(err, errA) {
+		t.Fatalf("got %v, want the first settled error", err)
+	}
+}
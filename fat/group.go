@@ -0,0 +1,156 @@
+package fat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+)
+
+// GroupMode decides how a Group reacts to a failing task
+type GroupMode int
+
+const (
+	// GroupFailFast cancels the group context on the first error and Wait returns that error
+	GroupFailFast GroupMode = iota
+	// GroupCollectAll lets every task run and Wait returns all errors joined
+	GroupCollectAll
+)
+
+type GroupOption func(*Group)
+
+func WithGroupPool(pool *ants.Pool) GroupOption {
+	return func(g *Group) {
+		g.pool = pool
+	}
+}
+
+func WithGroupMode(mode GroupMode) GroupOption {
+	return func(g *Group) {
+		g.mode = mode
+	}
+}
+
+// Group runs tasks on an ants pool and waits for all of them, like errgroup.
+// A panicking task is reported as a *PanicError instead of crashing the process.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	pool   *ants.Pool
+	mode   GroupMode
+	wg     sync.WaitGroup
+	sem    chan struct{}
+	mu     sync.Mutex
+	errs   []error
+}
+
+// NewGroup returns a Group and the context given to its tasks,
+// cancelled on the first error (GroupFailFast) or when Wait returns
+func NewGroup(ctx context.Context, opts ...GroupOption) (*Group, context.Context) {
+	group := &Group{
+		pool: getDefaultPool(),
+		mode: GroupFailFast,
+	}
+
+	for _, opt := range opts {
+		opt(group)
+	}
+
+	group.ctx, group.cancel = context.WithCancelCause(ctx)
+	return group, group.ctx
+}
+
+// SetLimit caps the number of tasks running at once, Go blocks while the limit is reached.
+// n <= 0 removes the limit. It must not be called while tasks are running.
+func (g *Group) SetLimit(n int) {
+	if n <= 0 {
+		g.sem = nil
+		return
+	}
+
+	g.sem = make(chan struct{}, n)
+}
+
+// Go runs fn on the pool, waiting for a free worker when the pool is saturated.
+// In GroupFailFast mode fn is skipped once the group context is done, Wait then reports the cause.
+func (g *Group) Go(fn func(ctx context.Context) error) {
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+
+	if g.mode == GroupFailFast && g.ctx.Err() != nil {
+		// a cancelled parent must not look like success, after a failure fail keeps the first error
+		g.fail(context.Cause(g.ctx))
+		g.done()
+		return
+	}
+
+	g.wg.Add(1)
+	queued := time.Now()
+	options := &AsyncOption{
+		ctx:        g.ctx,
+		pool:       g.pool,
+		saturation: SaturationBlock,
+	}
+
+	submit(options, func() {
+		defer g.wg.Done()
+		defer g.done()
+
+		g.fail(execute(queued, func() error {
+			return fn(g.ctx)
+		}))
+	}, func(err error) {
+		// record before Done so Wait sees it
+		rejected(queued, err)
+		g.fail(err)
+		g.done()
+		g.wg.Done()
+	})
+}
+
+// Wait blocks until every task returned and reports their errors following the group mode
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(nil)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.errs) == 0 {
+		return nil
+	}
+
+	if g.mode == GroupFailFast {
+		return g.errs[0]
+	}
+
+	return errors.Join(g.errs...)
+}
+
+func (g *Group) fail(err error) {
+	if err == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// once failed, the other tasks mostly report the cancellation itself
+	if g.mode == GroupFailFast && len(g.errs) > 0 {
+		return
+	}
+
+	g.errs = append(g.errs, err)
+	if g.mode == GroupFailFast {
+		g.cancel(err)
+	}
+}
+
+func (g *Group) done() {
+	if g.sem != nil {
+		<-g.sem
+	}
+}
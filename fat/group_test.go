@@ -0,0 +1,93 @@
+package fat
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupFailFast(t *testing.T) {
+	errFirst := errors.New("first")
+	group, ctx := NewGroup(context.Background())
+
+	group.Go(func(context.Context) error {
+		return errFirst
+	})
+	group.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if err := group.Wait(); !errors.Is(err, errFirst) {
+		t.Fatalf("got %v, want the first error", err)
+	}
+	if ctx.Err() == nil {
+		t.Fatal("group context not cancelled")
+	}
+}
+
+func TestGroupCancelledParent(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var ran atomic.Bool
+	group, _ := NewGroup(parent)
+	group.Go(func(context.Context) error {
+		ran.Store(true)
+		return nil
+	})
+
+	if err := group.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if ran.Load() {
+		t.Fatal("task ran on a cancelled group")
+	}
+}
+
+func TestGroupCollectAll(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	group, _ := NewGroup(context.Background(), WithGroupMode(GroupCollectAll))
+
+	group.Go(func(context.Context) error { return errA })
+	group.Go(func(context.Context) error { return errB })
+	group.Go(func(context.Context) error { panic("boom") })
+
+	err := group.Wait()
+
+	var panicErr *PanicError
+	if !errors.Is(err, errA) || !errors.Is(err, errB) || !errors.As(err, &panicErr) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestGroupLimit(t *testing.T) {
+	group, _ := NewGroup(context.Background())
+	group.SetLimit(2)
+
+	var running, peak atomic.Int32
+	for range 10 {
+		group.Go(func(context.Context) error {
+			current := running.Add(1)
+			for {
+				seen := peak.Load()
+				if current <= seen || peak.CompareAndSwap(seen, current) {
+					break
+				}
+			}
+
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("%d tasks ran at once, limit is 2", peak.Load())
+	}
+}
@@ -0,0 +1,88 @@
+package fat
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+type hedgeOutcome[T any] struct {
+	idx    int
+	result *T
+	err    error
+}
+
+// hedged runs the attempts overlapping, see WithHedge. Losers are cancelled through their
+// context but not waited for, and their late failures are not reported to the failure handler.
+func (f FallbackRunner[T]) hedged(ctx context.Context, args []func(ctx context.Context) (*T, error)) (*T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// buffered so a loser finishing after Run returned does not leak
+	outcomes := make(chan hedgeOutcome[T], len(args))
+	launch := func(idx int) {
+		queued := time.Now()
+		go func() {
+			result, err := f.attempt(ctx, queued, args[idx])
+			outcomes <- hedgeOutcome[T]{idx: idx, result: result, err: err}
+		}()
+	}
+
+	timer := time.NewTimer(f.options.hedge)
+	defer timer.Stop()
+
+	failed := &FallbackError{}
+	launch(0)
+	started, pending := 1, 1
+
+	for pending > 0 {
+		select {
+		case <-ctx.Done():
+			failed.Cause = ctx.Err()
+			return nil, failed.sorted()
+
+		case <-timer.C:
+			if started < len(args) {
+				launch(started)
+				started++
+				pending++
+				timer.Reset(f.options.hedge)
+			}
+
+		case outcome := <-outcomes:
+			pending--
+			if outcome.err == nil {
+				return outcome.result, nil
+			}
+
+			failed.Attempts = append(failed.Attempts, AttemptError{Attempt: outcome.idx, Err: outcome.err})
+			if f.options.onFailure != nil {
+				f.options.onFailure(outcome.idx, outcome.err)
+			}
+
+			if errors.Is(outcome.err, ErrBreakFallback) {
+				return nil, failed.sorted()
+			}
+
+			if started < len(args) {
+				launch(started)
+				started++
+				pending++
+				timer.Reset(f.options.hedge)
+			}
+		}
+	}
+
+	return nil, failed.sorted()
+}
+
+// sorted orders the attempts by index, they fail in any order when overlapping
+func (e *FallbackError) sorted() *FallbackError {
+	slices.SortFunc(e.Attempts, func(a, b AttemptError) int {
+		return cmp.Compare(a.Attempt, b.Attempt)
+	})
+
+	return e
+}
@@ -0,0 +1,128 @@
+package fat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// PanicError is returned in place of the error of a task that panicked
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("fat: task panic: %v", e.Value)
+}
+
+// Unwrap exposes the panic value when it is an error
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// TaskOutcome is how a task ended
+type TaskOutcome int
+
+const (
+	TaskSucceeded TaskOutcome = iota
+	TaskFailed
+	TaskCancelled // returned the context error
+	TaskPanicked
+	TaskRejected // never ran, the pool refused it
+)
+
+func (o TaskOutcome) String() string {
+	switch o {
+	case TaskSucceeded:
+		return "succeeded"
+	case TaskFailed:
+		return "failed"
+	case TaskCancelled:
+		return "cancelled"
+	case TaskPanicked:
+		return "panicked"
+	case TaskRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
+
+// TaskStats describes one task executed through the package
+type TaskStats struct {
+	Wait    time.Duration // from submission until the task started
+	Run     time.Duration
+	Outcome TaskOutcome
+	Err     error
+}
+
+// TaskObserver receives the stats of every task, it is called on the worker and must not block
+type TaskObserver func(stats TaskStats)
+
+var observer atomic.Pointer[TaskObserver]
+
+// SetTaskObserver installs a process wide observer, nil removes it
+func SetTaskObserver(fn TaskObserver) {
+	if fn == nil {
+		observer.Store(nil)
+		return
+	}
+	observer.Store(&fn)
+}
+
+// execute runs fn, converts a panic into a PanicError and reports the task to the observer
+func execute(queued time.Time, fn func() error) (err error) {
+	start := time.Now()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+
+		observe(TaskStats{
+			Wait:    start.Sub(queued),
+			Run:     time.Since(start),
+			Outcome: outcomeOf(err),
+			Err:     err,
+		})
+	}()
+
+	return fn()
+}
+
+// rejected reports a task the pool refused to run
+func rejected(queued time.Time, err error) {
+	observe(TaskStats{
+		Wait:    time.Since(queued),
+		Outcome: TaskRejected,
+		Err:     err,
+	})
+}
+
+func observe(stats TaskStats) {
+	if fn := observer.Load(); fn != nil {
+		(*fn)(stats)
+	}
+}
+
+func outcomeOf(err error) TaskOutcome {
+	var panicErr *PanicError
+
+	switch {
+	case err == nil:
+		return TaskSucceeded
+	case errors.As(err, &panicErr):
+		return TaskPanicked
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return TaskCancelled
+	default:
+		return TaskFailed
+	}
+}
@@ -0,0 +1,107 @@
+package fat
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/lamlv2305/toolkit/v2/slice"
+	"github.com/panjf2000/ants/v2"
+)
+
+type parallelOptions struct {
+	pool        *ants.Pool
+	concurrency int
+	chunkSize   int
+}
+
+type ParallelOption func(*parallelOptions)
+
+// WithConcurrency caps how many items (or chunks) are processed at once, GOMAXPROCS by default
+func WithConcurrency(n int) ParallelOption {
+	return func(o *parallelOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithChunkSize makes each pool task process size consecutive items, cheaper for tiny fn
+func WithChunkSize(size int) ParallelOption {
+	return func(o *parallelOptions) {
+		o.chunkSize = size
+	}
+}
+
+func WithParallelPool(pool *ants.Pool) ParallelOption {
+	return func(o *parallelOptions) {
+		o.pool = pool
+	}
+}
+
+// ParallelMap applies fn to every item on the pool, results keep the order of items.
+// It stops at the first error or when ctx ends and returns that error.
+func ParallelMap[T, R any](ctx context.Context, items []T, fn func(ctx context.Context, item T) (R, error), opts ...ParallelOption) ([]R, error) {
+	results := make([]R, len(items))
+
+	err := parallel(ctx, items, func(ctx context.Context, idx int, item T) error {
+		result, err := fn(ctx, item)
+		results[idx] = result
+		return err
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ParallelForEach calls fn for every item on the pool, see ParallelMap
+func ParallelForEach[T any](ctx context.Context, items []T, fn func(ctx context.Context, item T) error, opts ...ParallelOption) error {
+	return parallel(ctx, items, func(ctx context.Context, _ int, item T) error {
+		return fn(ctx, item)
+	}, opts)
+}
+
+func parallel[T any](ctx context.Context, items []T, fn func(ctx context.Context, idx int, item T) error, opts []ParallelOption) error {
+	options := &parallelOptions{
+		pool:        getDefaultPool(),
+		concurrency: runtime.GOMAXPROCS(0),
+		chunkSize:   1,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	group, groupCtx := NewGroup(ctx, WithGroupPool(options.pool))
+	group.SetLimit(options.concurrency)
+
+	offset := 0
+	for _, chunk := range slice.ChunkBy(items, max(options.chunkSize, 1)) {
+		if groupCtx.Err() != nil {
+			break
+		}
+
+		start := offset
+		offset += len(chunk)
+
+		group.Go(func(ctx context.Context) error {
+			for idx := range chunk {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+
+				if err := fn(ctx, start+idx, chunk[idx]); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		return err
+	}
+
+	// cancelled before any task noticed
+	return ctx.Err()
+}
@@ -0,0 +1,173 @@
+package fat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+)
+
+// SaturationPolicy decides what happens when the pool has no free worker
+type SaturationPolicy int
+
+const (
+	// SaturationFailFast returns ants.ErrPoolOverload (default)
+	SaturationFailFast SaturationPolicy = iota
+	// SaturationBlock waits for a free worker until the context ends
+	SaturationBlock
+	// SaturationCallerRuns runs the task in the calling goroutine
+	SaturationCallerRuns
+	// SaturationQueue keeps the task in a bounded backlog, see WithBacklog
+	SaturationQueue
+)
+
+// ErrBacklogFull is returned with SaturationQueue when the backlog has no room left
+var ErrBacklogFull = errors.New("fat: backlog full")
+
+func WithSaturation(policy SaturationPolicy) AsyncOptionFunc {
+	return func(opt *AsyncOption) {
+		opt.saturation = policy
+	}
+}
+
+// WithBacklog queues the task behind the others waiting for a saturated pool (SaturationQueue),
+// as long as fewer than size tasks are waiting. Tasks run in the order they were queued,
+// a new task does not skip the backlog even if a worker is free.
+func WithBacklog(size int) AsyncOptionFunc {
+	return func(opt *AsyncOption) {
+		opt.saturation = SaturationQueue
+		opt.backlog = size
+	}
+}
+
+// submit hands task to the pool following the saturation policy, reject receives the
+// error when the task will not run, either right away or when it leaves the backlog
+func submit(options *AsyncOption, task func(), reject func(err error)) {
+	if options.saturation == SaturationQueue {
+		if err := submitQueued(options.pool, options.backlog, queuedTask{run: task, reject: reject}); err != nil {
+			reject(err)
+		}
+		return
+	}
+
+	err := options.pool.Submit(task)
+	if errors.Is(err, ants.ErrPoolOverload) {
+		switch options.saturation {
+		case SaturationBlock:
+			err = submitBlocking(options.ctx, options.pool, task)
+
+		case SaturationCallerRuns:
+			task()
+			err = nil
+		}
+	}
+
+	if err != nil {
+		reject(err)
+	}
+}
+
+// submitBlocking retries with backoff, it works for non-blocking pools such as the default one
+func submitBlocking(ctx context.Context, pool *ants.Pool, task func()) error {
+	wait := time.Millisecond
+	for {
+		err := pool.Submit(task)
+		if !errors.Is(err, ants.ErrPoolOverload) {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		wait = min(wait*2, 50*time.Millisecond)
+	}
+}
+
+var (
+	backlogsMu sync.Mutex
+	backlogs   = map[*ants.Pool]*backlog{}
+)
+
+type queuedTask struct {
+	run    func()
+	reject func(err error)
+}
+
+// backlog feeds queued tasks to its pool in order, as soon as workers are free.
+// It exists while tasks are waiting and is removed once empty, or with its remaining
+// tasks when the pool is released.
+type backlog struct {
+	pool  *ants.Pool
+	tasks []queuedTask // guarded by backlogsMu
+}
+
+// submitQueued submits task unless tasks already wait for pool, then it queues behind them
+func submitQueued(pool *ants.Pool, size int, task queuedTask) error {
+	backlogsMu.Lock()
+	_, waiting := backlogs[pool]
+	backlogsMu.Unlock()
+
+	if !waiting {
+		// outside of the lock, a blocking pool may wait in Submit
+		err := pool.Submit(task.run)
+		if !errors.Is(err, ants.ErrPoolOverload) {
+			return err
+		}
+	}
+
+	backlogsMu.Lock()
+	defer backlogsMu.Unlock()
+
+	queue, ok := backlogs[pool]
+	if !ok {
+		queue = &backlog{pool: pool}
+		backlogs[pool] = queue
+
+		go queue.dispatch()
+	}
+
+	if len(queue.tasks) >= max(size, 1) {
+		return ErrBacklogFull
+	}
+
+	queue.tasks = append(queue.tasks, task)
+	return nil
+}
+
+func (b *backlog) dispatch() {
+	for {
+		backlogsMu.Lock()
+		if len(b.tasks) == 0 {
+			delete(backlogs, b.pool)
+			backlogsMu.Unlock()
+			return
+		}
+
+		task := b.tasks[0]
+		b.tasks[0] = queuedTask{}
+		b.tasks = b.tasks[1:]
+		backlogsMu.Unlock()
+
+		if err := submitBlocking(context.Background(), b.pool, task.run); err != nil {
+			// pool released, the callers get the error instead of a result
+			backlogsMu.Lock()
+			rest := b.tasks
+			b.tasks = nil
+			delete(backlogs, b.pool)
+			backlogsMu.Unlock()
+
+			task.reject(err)
+			for idx := range rest {
+				rest[idx].reject(err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package fat
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+)
+
+// busyPool returns a single worker pool kept busy until the returned func is called
+func busyPool(t *testing.T) (*ants.Pool, func()) {
+	t.Helper()
+
+	pool, err := ants.NewPool(1, ants.WithNonblocking(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block := make(chan struct{})
+	if err := pool.Submit(func() { <-block }); err != nil {
+		t.Fatal(err)
+	}
+
+	return pool, func() { close(block) }
+}
+
+func TestSaturationQueueKeepsOrder(t *testing.T) {
+	pool, unblock := busyPool(t)
+	defer pool.Release()
+
+	var mu sync.Mutex
+	var order []int
+	results := make([]chan error, 0, 6)
+
+	for idx := range 3 {
+		results = append(results, AsyncExec(func(context.Context) error {
+			mu.Lock()
+			order = append(order, idx)
+			mu.Unlock()
+			return nil
+		}, WithPool(pool), WithBacklog(8)))
+	}
+
+	unblock()
+
+	// the worker is free now, later tasks must still wait behind the backlog
+	for idx := 3; idx < 6; idx++ {
+		results = append(results, AsyncExec(func(context.Context) error {
+			mu.Lock()
+			order = append(order, idx)
+			mu.Unlock()
+			return nil
+		}, WithPool(pool), WithBacklog(8)))
+	}
+
+	for _, result := range results {
+		if err := <-result; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for idx := range order {
+		if order[idx] != idx {
+			t.Fatalf("run order %v", order)
+		}
+	}
+}
+
+func TestSaturationQueueFull(t *testing.T) {
+	pool, unblock := busyPool(t)
+	defer pool.Release()
+
+	first := AsyncExec(func(context.Context) error { return nil }, WithPool(pool), WithBacklog(1))
+	second := AsyncExec(func(context.Context) error { return nil }, WithPool(pool), WithBacklog(1))
+
+	if err := <-second; !errors.Is(err, ErrBacklogFull) {
+		t.Fatalf("got %v, want ErrBacklogFull", err)
+	}
+
+	unblock()
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaturationQueueReleasedPool(t *testing.T) {
+	pool, unblock := busyPool(t)
+
+	results := []chan error{
+		AsyncExec(func(context.Context) error { return nil }, WithPool(pool), WithBacklog(4)),
+		AsyncExec(func(context.Context) error { return nil }, WithPool(pool), WithBacklog(4)),
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	pool.Release()
+	unblock()
+
+	for _, result := range results {
+		if err := <-result; !errors.Is(err, ants.ErrPoolClosed) {
+			t.Fatalf("got %v, want ErrPoolClosed", err)
+		}
+	}
+
+	backlogsMu.Lock()
+	defer backlogsMu.Unlock()
+	if _, ok := backlogs[pool]; ok {
+		t.Fatal("backlog of a released pool kept")
+	}
+}
@@ -1,8 +1,23 @@
 package toolkit
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrInvalidValue   = errors.New("holder: invalid value")
+	ErrNoRollback     = errors.New("holder: nothing to roll back")
+	ErrRollbackClosed = errors.New("holder: previous value already closed")
+	ErrHolderClosed   = errors.New("holder: closed")
+	// ErrUnchanged is returned when WithEqual skipped the value, the caller still owns it
+	ErrUnchanged = errors.New("holder: value unchanged")
 )
 
 type WithHolder[T any] func(*Holder[T])
@@ -13,20 +28,87 @@ func WithCloser[T any](closer CloserFunc[T]) WithHolder[T] {
 	}
 }
 
+// WithGrace is the minimum delay before an old value is closed, 5s by default, so callers
+// still using a value from Get are not cut off. It is also how long leases are waited for
+// unless WithLeaseTimeout is set: past it the value is closed anyway and a warning is logged.
+// <= 0 closes as soon as the leases are released, waiting for all of them.
 func WithGrace[T any](grace time.Duration) WithHolder[T] {
 	return func(h *Holder[T]) {
 		h.grace = grace
 	}
 }
 
+// WithLeaseTimeout bounds how long an old value waits for its leases before being closed anyway,
+// counted from the Set, the grace period by default. <= 0 waits for every lease to be released.
+func WithLeaseTimeout[T any](timeout time.Duration) WithHolder[T] {
+	return func(h *Holder[T]) {
+		h.leaseTimeout = timeout
+		h.leaseTimeoutSet = true
+	}
+}
+
+// WithEqual skips Set when the new value is equal to the current one, Set returns
+// ErrUnchanged: no version bump, no closer and no notification
+func WithEqual[T any](equal func(a, b *T) bool) WithHolder[T] {
+	return func(h *Holder[T]) {
+		h.equal = equal
+	}
+}
+
+// WithValidator rejects a value before it is published, Set returns the error wrapped in ErrInvalidValue
+func WithValidator[T any](validator func(*T) error) WithHolder[T] {
+	return func(h *Holder[T]) {
+		h.validator = validator
+	}
+}
+
+// WatchFunc is notified after every swap with the replaced value (nil on the first Set)
+// and the version of the new one
+type WatchFunc[T any] func(old, new *T, version uint64)
+
 // CloserFunc is optional cleanup logic (e.g., close old DB/Redis connections).
 type CloserFunc[T any] func(old *T)
 
 // Holder provides lock-free read + atomic swap on reload.
 type Holder[T any] struct {
-	value  atomic.Value // holds *T
-	closer CloserFunc[T]
-	grace  time.Duration
+	mu              sync.Mutex // serializes Set so versions and notifications are ordered
+	current         atomic.Pointer[generation[T]]
+	closer          CloserFunc[T]
+	equal           func(a, b *T) bool
+	grace           time.Duration
+	leaseTimeout    time.Duration
+	leaseTimeoutSet bool
+	validator       func(*T) error
+	previous        *generation[T] // replaced by the last Set, target of Rollback
+	watchers        map[int]WatchFunc[T]
+	nextID          int
+	closed          bool
+	pending         sync.WaitGroup // background closers, awaited by Close
+}
+
+// generation is one stored value and the leases taken on it
+type generation[T any] struct {
+	value   *T
+	version uint64
+	state   atomic.Int32  // genPending until closed or rolled back
+	cancel  chan struct{} // closed by Rollback to stop the pending close
+	refs    atomic.Int64
+	retired atomic.Bool
+	drained chan struct{} // closed once retired and refs is back to zero
+	once    sync.Once
+	pinned  []*generation[T] // rolled back generations of the same value with leases left
+}
+
+const (
+	genPending int32 = iota
+	genClosing
+	genCancelled
+)
+
+// Lease keeps the value it was acquired on from being closed until Release
+type Lease[T any] struct {
+	gen  *generation[T]
+	once sync.Once
 }
 
 // NewHolder creates a new hot-reload holder with optional cleanup.
@@ -43,28 +125,338 @@ func NewHolder[T any](opts ...WithHolder[T]) *Holder[T] {
 	return holder
 }
 
+// Get returns the current value without tracking it, a replaced value is only protected
+// by the grace period so a caller using it longer should Acquire instead
 func (h *Holder[T]) Get() *T {
-	val := h.value.Load()
-	if val == nil {
+	gen := h.current.Load()
+	if gen == nil {
+		return nil
+	}
+	return gen.value
+}
+
+// Version returns the version of the current value, it starts at 1 and grows on every Set, 0 when empty
+func (h *Holder[T]) Version() uint64 {
+	gen := h.current.Load()
+	if gen == nil {
+		return 0
+	}
+	return gen.version
+}
+
+// Watch registers fn to be called after every Set, in version order.
+// fn runs on the Set goroutine and must not call Set or Watch. The returned func unregisters it.
+func (h *Holder[T]) Watch(fn WatchFunc[T]) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.watchers == nil {
+		h.watchers = map[int]WatchFunc[T]{}
+	}
+
+	id := h.nextID
+	h.nextID++
+	h.watchers[id] = fn
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		delete(h.watchers, id)
+	}
+}
+
+// Acquire leases the current value, the CloserFunc of that value waits for Release
+func (h *Holder[T]) Acquire() *Lease[T] {
+	for {
+		gen := h.current.Load()
+		if gen == nil {
+			return &Lease[T]{}
+		}
+
+		gen.refs.Add(1)
+		if h.current.Load() == gen {
+			return &Lease[T]{gen: gen}
+		}
+
+		// swapped in the meantime, lease the new value instead
+		gen.release()
+	}
+}
+
+// Value returns the leased value, nil when the holder was empty
+func (l *Lease[T]) Value() *T {
+	if l.gen == nil {
+		return nil
+	}
+	return l.gen.value
+}
+
+// Release ends the lease, calling it more than once is a no-op
+func (l *Lease[T]) Release() {
+	l.once.Do(func() {
+		if l.gen != nil {
+			l.gen.release()
+		}
+	})
+}
+
+// Set validates and publishes newVal, the previous value is closed after the grace period
+// once its leases are released
+func (h *Holder[T]) Set(newVal *T) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return ErrHolderClosed
+	}
+
+	old := h.current.Load()
+	if old != nil && h.equal != nil && h.equal(old.value, newVal) {
+		return ErrUnchanged
+	}
+
+	if err := h.validate(newVal); err != nil {
+		return err
+	}
+
+	h.publish(old, newVal, nil)
+	return nil
+}
+
+// CompareAndSwap publishes newVal only if old is still the current value, ErrUnchanged when WithEqual skips it
+func (h *Holder[T]) CompareAndSwap(old, newVal *T) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return false, ErrHolderClosed
+	}
+
+	current := h.current.Load()
+	if current.valueOrNil() != old {
+		return false, nil
+	}
+
+	if current != nil && h.equal != nil && h.equal(current.value, newVal) {
+		return false, ErrUnchanged
+	}
+
+	if err := h.validate(newVal); err != nil {
+		return false, err
+	}
+
+	h.publish(current, newVal, nil)
+	return true, nil
+}
+
+// Rollback restores the value replaced by the last Set as long as it has not been closed yet,
+// the rolled back value is closed like any replaced value. Only one step is kept.
+func (h *Holder[T]) Rollback() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return ErrHolderClosed
+	}
+
+	previous := h.previous
+	if previous == nil {
+		return ErrNoRollback
+	}
+
+	if !previous.state.CompareAndSwap(genPending, genCancelled) {
+		return ErrRollbackClosed
+	}
+	close(previous.cancel)
+
+	// leases taken on previous still hold the value, the new generation waits for them too
+	h.publish(h.current.Load(), previous.value, previous.stillLeased())
+	h.previous = nil
+
+	return nil
+}
+
+// discard closes a value that was never published, e.g. skipped by WithEqual
+func (h *Holder[T]) discard(value *T) {
+	if value != nil && h.closer != nil {
+		h.closer(value)
+	}
+}
+
+func (h *Holder[T]) validate(newVal *T) error {
+	if h.validator == nil {
+		return nil
+	}
+
+	if err := h.validator(newVal); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidValue, err)
+	}
+
+	return nil
+}
+
+// publish stores newVal as the next version and retires old, must be called with mu held
+func (h *Holder[T]) publish(old *generation[T], newVal *T, pinned []*generation[T]) {
+	gen := &generation[T]{
+		value:   newVal,
+		version: 1,
+		drained: make(chan struct{}),
+		cancel:  make(chan struct{}),
+		pinned:  pinned,
+	}
+	if old != nil {
+		gen.version = old.version + 1
+	}
+
+	h.current.Store(gen)
+	h.previous = old
+
+	for _, fn := range h.watchers {
+		fn(old.valueOrNil(), newVal, gen.version)
+	}
+
+	h.retire(old)
+}
+
+// Close empties the holder, closes the current value like Set would (grace then leases) and
+// waits for every pending closer or ctx, whichever comes first. Set fails afterwards.
+func (h *Holder[T]) Close(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		h.previous = nil
+		h.retire(h.current.Swap(nil))
+	}
+	h.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		h.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
 		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	return val.(*T)
 }
 
-func (h *Holder[T]) Set(newVal *T) {
-	oldVal := h.Get()
-	h.value.Store(newVal)
+// retire schedules the close of a replaced generation, must be called with mu held
+func (h *Holder[T]) retire(old *generation[T]) {
+	if old == nil {
+		return
+	}
+
+	old.retire()
 
-	if oldVal != nil && h.closer != nil {
-		// Close old resource in background after grace period
-		go func(old *T) {
-			if h.grace > 0 {
-				time.Sleep(h.grace)
-			}
+	if old.value != nil && h.closer != nil {
+		// Close old resource in background once its leases are released
+		h.pending.Add(1)
+		go h.close(old)
+	}
+}
+
+func (h *Holder[T]) close(old *generation[T]) {
+	defer h.pending.Done()
+
+	start := time.Now()
+
+	// Get readers are not tracked, give them the grace period in any case
+	if h.grace > 0 {
+		timer := time.NewTimer(h.grace)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-old.cancel:
+			return
+		}
+	}
+
+	var timeout <-chan time.Time
+	leaseTimeout := h.grace
+	if h.leaseTimeoutSet {
+		leaseTimeout = h.leaseTimeout
+	}
+
+	if leaseTimeout > 0 {
+		timer := time.NewTimer(leaseTimeout - time.Since(start))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+wait:
+	for _, gen := range append([]*generation[T]{old}, old.pinned...) {
+		// drained wins over a timeout that fired at the same time
+		select {
+		case <-gen.drained:
+			continue
+		default:
+		}
+
+		select {
+		case <-gen.drained:
+		case <-old.cancel:
+			return
+		case <-timeout:
+			log.Warn().
+				Int64("leases", old.leases()).
+				Dur("timeout", leaseTimeout).
+				Msg("Holder lease timeout exceeded, closing value still in use")
+			break wait
+		}
+	}
+
+	// Rollback may have won the race
+	if !old.state.CompareAndSwap(genPending, genClosing) {
+		return
+	}
+
+	h.closer(old.value)
+}
+
+func (g *generation[T]) valueOrNil() *T {
+	if g == nil {
+		return nil
+	}
+	return g.value
+}
+
+// stillLeased returns g and the generations it pins that are not drained yet
+func (g *generation[T]) stillLeased() []*generation[T] {
+	var result []*generation[T]
+	for _, gen := range append([]*generation[T]{g}, g.pinned...) {
+		select {
+		case <-gen.drained:
+		default:
+			result = append(result, gen)
+		}
+	}
+
+	return result
+}
+
+func (g *generation[T]) leases() int64 {
+	count := g.refs.Load()
+	for _, gen := range g.pinned {
+		count += gen.refs.Load()
+	}
+
+	return count
+}
+
+func (g *generation[T]) release() {
+	if g.refs.Add(-1) == 0 && g.retired.Load() {
+		g.once.Do(func() { close(g.drained) })
+	}
+}
 
-			if h.closer != nil {
-				h.closer(old)
-			}
-		}(oldVal)
+func (g *generation[T]) retire() {
+	g.retired.Store(true)
+	if g.refs.Load() == 0 {
+		g.once.Do(func() { close(g.drained) })
 	}
 }
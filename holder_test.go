@@ -0,0 +1,226 @@
+package toolkit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type holderValue struct {
+	n int
+}
+
+// closeLog records the values handed to the closer
+type closeLog struct {
+	mu     sync.Mutex
+	values []int
+}
+
+func (c *closeLog) closer(value *holderValue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values = append(c.values, value.n)
+}
+
+func (c *closeLog) closed(n int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, value := range c.values {
+		if value == n {
+			return true
+		}
+	}
+	return false
+}
+
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHolderGraceBoundsLeases(t *testing.T) {
+	log := &closeLog{}
+	holder := NewHolder(WithCloser(log.closer), WithGrace[holderValue](20*time.Millisecond))
+
+	_ = holder.Set(&holderValue{n: 1})
+	holder.Acquire() // never released
+	_ = holder.Set(&holderValue{n: 2})
+
+	time.Sleep(5 * time.Millisecond)
+	if log.closed(1) {
+		t.Fatal("closed before the grace period")
+	}
+
+	eventually(t, func() bool { return log.closed(1) })
+
+	if err := holder.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHolderLeaseTimeout(t *testing.T) {
+	log := &closeLog{}
+	holder := NewHolder(
+		WithCloser(log.closer),
+		WithGrace[holderValue](time.Millisecond),
+		WithLeaseTimeout[holderValue](0),
+	)
+
+	_ = holder.Set(&holderValue{n: 1})
+	lease := holder.Acquire()
+	_ = holder.Set(&holderValue{n: 2})
+
+	time.Sleep(20 * time.Millisecond)
+	if log.closed(1) {
+		t.Fatal("closed while leased")
+	}
+
+	lease.Release()
+	eventually(t, func() bool { return log.closed(1) })
+}
+
+func TestHolderRollbackKeepsLeases(t *testing.T) {
+	log := &closeLog{}
+	holder := NewHolder(WithCloser(log.closer), WithGrace[holderValue](time.Millisecond), WithLeaseTimeout[holderValue](0))
+
+	_ = holder.Set(&holderValue{n: 1})
+	lease := holder.Acquire()
+	_ = holder.Set(&holderValue{n: 2})
+
+	if err := holder.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if got := holder.Get().n; got != 1 {
+		t.Fatalf("got %d after rollback, want 1", got)
+	}
+
+	_ = holder.Set(&holderValue{n: 3})
+	eventually(t, func() bool { return log.closed(2) })
+
+	time.Sleep(20 * time.Millisecond)
+	if log.closed(1) {
+		t.Fatal("rolled back value closed while a lease taken before the rollback is held")
+	}
+
+	lease.Release()
+	eventually(t, func() bool { return log.closed(1) })
+}
+
+func TestHolderRollbackAfterClose(t *testing.T) {
+	log := &closeLog{}
+	holder := NewHolder(WithCloser(log.closer), WithGrace[holderValue](time.Millisecond))
+
+	if err := holder.Rollback(); !errors.Is(err, ErrNoRollback) {
+		t.Fatalf("got %v, want ErrNoRollback", err)
+	}
+
+	_ = holder.Set(&holderValue{n: 1})
+	_ = holder.Set(&holderValue{n: 2})
+	eventually(t, func() bool { return log.closed(1) })
+
+	if err := holder.Rollback(); !errors.Is(err, ErrRollbackClosed) {
+		t.Fatalf("got %v, want ErrRollbackClosed", err)
+	}
+	if got := holder.Get().n; got != 2 {
+		t.Fatalf("got %d, want 2", got)
+	}
+}
+
+func TestHolderCompareAndSwap(t *testing.T) {
+	holder := NewHolder[holderValue](WithGrace[holderValue](0))
+
+	first := &holderValue{n: 1}
+	_ = holder.Set(first)
+
+	swapped, err := holder.CompareAndSwap(&holderValue{n: 1}, &holderValue{n: 2})
+	if err != nil || swapped {
+		t.Fatalf("mismatch swapped=%v err=%v", swapped, err)
+	}
+	if holder.Get() != first || holder.Version() != 1 {
+		t.Fatal("mismatch changed the value")
+	}
+
+	swapped, err = holder.CompareAndSwap(first, &holderValue{n: 2})
+	if err != nil || !swapped {
+		t.Fatalf("match swapped=%v err=%v", swapped, err)
+	}
+	if holder.Version() != 2 {
+		t.Fatalf("version %d, want 2", holder.Version())
+	}
+}
+
+func TestHolderValidatorRejects(t *testing.T) {
+	errNegative := errors.New("negative")
+	log := &closeLog{}
+	holder := NewHolder(
+		WithCloser(log.closer),
+		WithValidator(func(value *holderValue) error {
+			if value.n < 0 {
+				return errNegative
+			}
+			return nil
+		}),
+	)
+
+	_ = holder.Set(&holderValue{n: 1})
+
+	err := holder.Set(&holderValue{n: -1})
+	if !errors.Is(err, ErrInvalidValue) || !errors.Is(err, errNegative) {
+		t.Fatalf("got %v", err)
+	}
+	if holder.Get().n != 1 || holder.Version() != 1 {
+		t.Fatal("rejected value was published")
+	}
+
+	reloader := NewReloader(holder, func(context.Context) (*holderValue, error) {
+		return &holderValue{n: -2}, nil
+	})
+	if err := reloader.Reload(context.Background()); !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("got %v", err)
+	}
+	if !log.closed(-2) {
+		t.Fatal("value rejected on reload was not closed")
+	}
+}
+
+func TestHolderCloseWaitsForClosers(t *testing.T) {
+	log := &closeLog{}
+	holder := NewHolder(WithCloser(log.closer), WithGrace[holderValue](10*time.Millisecond), WithLeaseTimeout[holderValue](0))
+
+	_ = holder.Set(&holderValue{n: 1})
+	_ = holder.Set(&holderValue{n: 2})
+	lease := holder.Acquire()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	if err := holder.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v while a lease is held", err)
+	}
+	if !log.closed(1) || log.closed(2) {
+		t.Fatal("only the replaced value should be closed so far")
+	}
+
+	lease.Release()
+	if err := holder.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !log.closed(2) {
+		t.Fatal("Close returned before the closer ran")
+	}
+
+	if err := holder.Set(&holderValue{n: 3}); !errors.Is(err, ErrHolderClosed) {
+		t.Fatalf("got %v after Close", err)
+	}
+}
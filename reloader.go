@@ -0,0 +1,167 @@
+package toolkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Loader builds a fresh value, e.g. by reading a config file and opening its connections.
+type Loader[T any] func(ctx context.Context) (*T, error)
+
+type WithReloader[T any] func(*Reloader[T])
+
+// WithInterval reloads every interval
+func WithInterval[T any](interval time.Duration) WithReloader[T] {
+	return func(r *Reloader[T]) {
+		r.interval = interval
+	}
+}
+
+// WithWatchFile reloads when the modification time or size of path changes.
+// The file is polled every poll (1s when <= 0) so it works without inotify.
+func WithWatchFile[T any](path string, poll time.Duration) WithReloader[T] {
+	return func(r *Reloader[T]) {
+		if poll <= 0 {
+			poll = time.Second
+		}
+
+		r.files = append(r.files, path)
+		if r.poll == 0 || poll < r.poll {
+			r.poll = poll
+		}
+	}
+}
+
+// WithReloadError is called every time the loader fails, the Holder keeps its current value
+func WithReloadError[T any](fn func(err error)) WithReloader[T] {
+	return func(r *Reloader[T]) {
+		r.onError = fn
+	}
+}
+
+// Reloader refreshes a Holder from a Loader periodically and/or when watched files change.
+type Reloader[T any] struct {
+	mu       sync.Mutex // one load at a time
+	holder   *Holder[T]
+	loader   Loader[T]
+	interval time.Duration
+	files    []string
+	poll     time.Duration
+	onError  func(err error)
+}
+
+func NewReloader[T any](holder *Holder[T], loader Loader[T], opts ...WithReloader[T]) *Reloader[T] {
+	reloader := &Reloader[T]{
+		holder: holder,
+		loader: loader,
+	}
+
+	for _, opt := range opts {
+		opt(reloader)
+	}
+
+	return reloader
+}
+
+// Reload loads a new value and stores it in the Holder, on error (loader or validator) the current value is kept.
+// A value that is not stored is closed right away, Reload returns nil when it was only unchanged (WithEqual).
+func (r *Reloader[T]) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, err := r.loader(ctx)
+	if err != nil {
+		return err
+	}
+
+	if value == nil {
+		return errors.New("loader returned a nil value")
+	}
+
+	if err := r.holder.Set(value); err != nil {
+		// rejected, unchanged or holder closed, the loader opened resources nobody will close
+		r.holder.discard(value)
+		if errors.Is(err, ErrUnchanged) {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Run reloads on the configured triggers until ctx is done, errors go to WithReloadError.
+// It does not load on start, call Reload first to fail fast on a broken initial value.
+func (r *Reloader[T]) Run(ctx context.Context) {
+	var tick <-chan time.Time
+	if r.interval > 0 {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	var poll <-chan time.Time
+	states := make([]fileState, len(r.files))
+	if len(r.files) > 0 {
+		for idx := range r.files {
+			states[idx] = statFile(r.files[idx])
+		}
+
+		ticker := time.NewTicker(r.poll)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-tick:
+			r.reload(ctx)
+
+		case <-poll:
+			changed := false
+			for idx := range r.files {
+				state := statFile(r.files[idx])
+				if state != states[idx] {
+					states[idx] = state
+					changed = true
+				}
+			}
+
+			if changed {
+				r.reload(ctx)
+			}
+		}
+	}
+}
+
+func (r *Reloader[T]) reload(ctx context.Context) {
+	if err := r.Reload(ctx); err != nil && r.onError != nil {
+		r.onError(fmt.Errorf("reload: %w", err))
+	}
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		exists:  true,
+	}
+}